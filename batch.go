@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Параметры накопления пакетов по умолчанию
+const (
+	DefaultBatchRecords  = 100
+	DefaultBatchBytes    = 1 << 20
+	DefaultBatchInterval = time.Second
+	DefaultBatchQueue    = 16
+)
+
+// BatchConfig параметры накопления записей в пакеты. Пакет отправляется, когда
+// достигнут любой из пределов по количеству, объему или времени
+type BatchConfig struct {
+	// MaxRecords максимальное количество записей в пакете, по умолчанию DefaultBatchRecords
+	MaxRecords int
+	// MaxBytes максимальный объем пакета в байтах, по умолчанию DefaultBatchBytes
+	MaxBytes int
+	// Interval максимальное время накопления пакета, по умолчанию DefaultBatchInterval
+	Interval time.Duration
+	// QueueSize количество готовых пакетов, ожидающих отправки, по умолчанию
+	// DefaultBatchQueue. При переполнении очереди пакеты отбрасываются
+	QueueSize int
+}
+
+// withDefaults возвращает параметры с подставленными значениями по умолчанию
+func (c BatchConfig) withDefaults() BatchConfig {
+	if c.MaxRecords <= 0 {
+		c.MaxRecords = DefaultBatchRecords
+	}
+	if c.MaxBytes <= 0 {
+		c.MaxBytes = DefaultBatchBytes
+	}
+	if c.Interval <= 0 {
+		c.Interval = DefaultBatchInterval
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultBatchQueue
+	}
+	return c
+}
+
+// batchEntry запись пакета с заранее закодированным представлением
+type batchEntry struct {
+	record *Record
+	data   []byte
+}
+
+// batcher накапливает записи и передает готовые пакеты функции отправки
+// в отдельной горутине. Close отправляет накопленное и дожидается завершения
+type batcher struct {
+	dropped uint64 // первое поле для выравнивания атомарного счетчика на 32-битных платформах
+	cfg     BatchConfig
+	flush   func([]batchEntry)
+	pending []batchEntry
+	size    int
+	queue   chan []batchEntry
+	timer   *time.Timer
+	done    chan struct{}
+	mu      sync.Mutex
+	closed  bool
+}
+
+// newBatcher запускает горутину отправки пакетов
+func newBatcher(cfg BatchConfig, flush func([]batchEntry)) *batcher {
+	b := &batcher{
+		cfg:   cfg.withDefaults(),
+		flush: flush,
+		done:  make(chan struct{}),
+	}
+	b.queue = make(chan []batchEntry, b.cfg.QueueSize)
+	go func() {
+		defer close(b.done)
+		for batch := range b.queue {
+			b.flush(batch)
+		}
+	}()
+	return b
+}
+
+// add добавляет запись в текущий пакет; возвращает false, если пакетировщик закрыт
+func (b *batcher) add(e batchEntry) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return false
+	}
+	if len(b.pending) > 0 && b.size+len(e.data) > b.cfg.MaxBytes {
+		b.cut(false)
+	}
+	b.pending = append(b.pending, e)
+	b.size += len(e.data)
+	if len(b.pending) >= b.cfg.MaxRecords || b.size >= b.cfg.MaxBytes {
+		b.cut(false)
+	} else if b.timer == nil {
+		b.timer = time.AfterFunc(b.cfg.Interval, b.tick)
+	}
+	return true
+}
+
+// tick отправляет пакет по истечении интервала накопления
+func (b *batcher) tick() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.timer = nil
+	if !b.closed {
+		b.cut(false)
+	}
+}
+
+// cut передает текущий пакет в очередь отправки. Вызывается под блокировкой;
+// при wait ожидает места в очереди, иначе отбрасывает пакет при переполнении
+func (b *batcher) cut(wait bool) {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.pending) == 0 {
+		return
+	}
+	batch := b.pending
+	b.pending = nil
+	b.size = 0
+
+	if wait {
+		b.queue <- batch
+		return
+	}
+	select {
+	case b.queue <- batch:
+	default:
+		atomic.AddUint64(&b.dropped, uint64(len(batch)))
+	}
+}
+
+// Dropped возвращает количество записей, отброшенных из-за переполнения очереди
+func (b *batcher) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+// close отправляет накопленные записи и дожидается обработки очереди
+func (b *batcher) close() {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		b.cut(true)
+		close(b.queue)
+	}
+	b.mu.Unlock()
+	<-b.done
+}
+
+// reportSinkError сообщает в stderr об ошибке назначения, возникшей вне вызова Write
+func reportSinkError(name string, err error) {
+	fmt.Fprintf(os.Stderr, "ошибка назначения логгера %s: %v\n", name, err)
+}
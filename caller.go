@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// callerDepth количество кадров стека между Logger.output и кодом пользователя:
+// output <- log/logf <- публичный метод (Info, Infof или пакетная функция) <- вызывающий код
+const callerDepth = 3
+
+// Caller описывает место в исходном коде, откуда была создана запись
+type Caller struct {
+	File     string
+	Function string
+	Line     int
+}
+
+// String возвращает место вызова в формате "каталог/файл.go:строка"
+func (c *Caller) String() string {
+	return trimCallerPath(c.File) + ":" + strconv.Itoa(c.Line)
+}
+
+// captureCaller определяет место вызова, пропуская skip кадров над вызывающей функцией
+func captureCaller(skip int) *Caller {
+	pc, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return nil
+	}
+
+	caller := &Caller{File: file, Line: line}
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		caller.Function = fn.Name()
+	}
+	return caller
+}
+
+// trimCallerPath оставляет от пути только последний каталог и имя файла
+func trimCallerPath(path string) string {
+	idx := strings.LastIndexByte(path, '/')
+	if idx == -1 {
+		return path
+	}
+	idx = strings.LastIndexByte(path[:idx], '/')
+	if idx == -1 {
+		return path
+	}
+	return path[idx+1:]
+}
+
+// WithCaller создает новый логгер, добавляющий к записям место вызова (файл:строка и функцию)
+func (l *Logger) WithCaller(enabled bool) ILogger {
+	newLogger := l.clone()
+	newLogger.addCaller = enabled
+	return newLogger
+}
+
+// WithCallerSkip создает новый логгер, пропускающий дополнительно skip кадров стека
+// при определении места вызова. Используется в собственных обертках над логгером:
+// обертка глубиной в одну функцию должна вызвать WithCallerSkip(1).
+// Значения накапливаются при вложенных вызовах
+func (l *Logger) WithCallerSkip(skip int) ILogger {
+	newLogger := l.clone()
+	newLogger.callerSkip += skip
+	return newLogger
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// currentLine возвращает номер строки, из которой вызвана функция
+func currentLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+// wrapperInfo имитирует пользовательскую обертку над логгером
+func wrapperInfo(l ILogger, msg string) {
+	l.Info(msg)
+}
+
+func TestCallerAnnotation(t *testing.T) {
+	t.Run("прямые методы", func(t *testing.T) {
+		l := New().WithCaller(true)
+
+		line := currentLine() + 1
+		l.Info("info message")
+		l.Warningf("warning %d", 1)
+
+		messages := l.GetMessages()
+		require.Len(t, messages, 2)
+		assert.Contains(t, messages[0], "caller_test.go:"+strconv.Itoa(line))
+		assert.Contains(t, messages[0], "func=github.com/ipiton/logger/v1.TestCallerAnnotation")
+		assert.Contains(t, messages[1], "caller_test.go:"+strconv.Itoa(line+1))
+	})
+
+	t.Run("пакетные функции", func(t *testing.T) {
+		originalLogger := globalLogger
+		defer func() { globalLogger = originalLogger }()
+
+		l := New().WithCaller(true)
+		SetGlobalLogger(l)
+
+		line := currentLine() + 1
+		Info("global message")
+		Errorf("global %s", "error")
+
+		messages := l.GetMessages()
+		require.Len(t, messages, 2)
+		assert.Contains(t, messages[0], "caller_test.go:"+strconv.Itoa(line))
+		assert.Contains(t, messages[1], "caller_test.go:"+strconv.Itoa(line+1))
+	})
+
+	t.Run("пользовательская обертка", func(t *testing.T) {
+		l := New().WithCaller(true).WithCallerSkip(1)
+
+		line := currentLine() + 1
+		wrapperInfo(l, "wrapped message")
+
+		messages := l.GetMessages()
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], "caller_test.go:"+strconv.Itoa(line))
+	})
+
+	t.Run("место вызова выключено по умолчанию", func(t *testing.T) {
+		l := New()
+		l.Info("no caller")
+
+		messages := l.GetMessages()
+		require.Len(t, messages, 1)
+		assert.False(t, strings.Contains(messages[0], CallerKey+"="))
+	})
+
+	t.Run("наследование дочерними логгерами", func(t *testing.T) {
+		l := New().WithCaller(true).WithPrefix("CHILD")
+
+		line := currentLine() + 1
+		l.Info("child message")
+
+		messages := l.GetMessages()
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], "caller_test.go:"+strconv.Itoa(line))
+	})
+}
+
+func TestTrimCallerPath(t *testing.T) {
+	assert.Equal(t, "pkg/file.go", trimCallerPath("/home/user/src/pkg/file.go"))
+	assert.Equal(t, "pkg/file.go", trimCallerPath("pkg/file.go"))
+	assert.Equal(t, "file.go", trimCallerPath("file.go"))
+}
+
+func TestCallerThroughInterface(t *testing.T) {
+	var l ILogger = New()
+	l = l.WithCaller(true)
+
+	line := currentLine() + 1
+	l.Info("message")
+
+	messages := l.GetMessages()
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "caller_test.go:"+strconv.Itoa(line))
+}
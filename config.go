@@ -26,6 +26,67 @@ func (c *Config) Override(other *Config) {
 		c.Level = other.Level
 	}
 
+	if other.Format != "" {
+		c.Format = other.Format
+	}
+
+	if other.ControlChars != "" {
+		c.ControlChars = other.ControlChars
+	}
+
+	c.overridePipeline(other)
+	c.overrideOutputs(other)
+}
+
+// overridePipeline переопределяет параметры обработки записей
+func (c *Config) overridePipeline(other *Config) {
+	if other.Redact != nil {
+		c.Redact = other.Redact
+	}
+
+	if other.Sampling != nil {
+		c.Sampling = other.Sampling
+	}
+
+	if other.RateLimit != nil {
+		c.RateLimit = other.RateLimit
+	}
+
+	if other.DedupWindow != 0 {
+		c.DedupWindow = other.DedupWindow
+	}
+
+	if other.StacktraceLevel != "" {
+		c.StacktraceLevel = other.StacktraceLevel
+	}
+
+	if other.Caller {
+		c.Caller = true
+	}
+
+	if other.Filters != nil {
+		c.Filters = other.Filters
+	}
+
+	if other.Metrics {
+		c.Metrics = true
+	}
+}
+
+// overrideOutputs переопределяет параметры вывода и файлы
+func (c *Config) overrideOutputs(other *Config) {
+	if other.SplitStderr {
+		c.SplitStderr = true
+	}
+
+	if other.StderrLevel != "" {
+		c.StderrLevel = other.StderrLevel
+	}
+
+	if other.FileSinks != nil {
+		c.FileSinks = other.FileSinks
+	}
+
 	if other.Files != nil {
 		if c.Files == nil {
 			c.Files = make(map[string]string)
@@ -38,6 +99,22 @@ func (c *Config) Override(other *Config) {
 
 // Validate проверяет корректность конфигурации
 func (c *Config) Validate() error {
+	for _, validate := range []func() error{c.validateFormat, c.validatePipeline, c.validateOutputs} {
+		if err := validate(); err != nil {
+			return err
+		}
+	}
+
+	// Проверяем наличие основного файла лога
+	if _, ok := c.Files["main"]; !ok {
+		return fmt.Errorf("не указан путь к основному файлу лога")
+	}
+
+	return nil
+}
+
+// validateFormat проверяет уровень логирования и параметры представления записей
+func (c *Config) validateFormat() error {
 	// Проверяем уровень логирования
 	validLevels := map[string]bool{
 		"debug":   true,
@@ -50,10 +127,72 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("некорректный уровень логирования: %s", c.Level)
 	}
 
-	// Проверяем наличие основного файла лога
-	if _, ok := c.Files["main"]; !ok {
-		return fmt.Errorf("не указан путь к основному файлу лога")
+	// Проверяем формат вывода
+	switch strings.ToLower(c.Format) {
+	case "", FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("некорректный формат вывода: %s", c.Format)
+	}
+
+	// Проверяем политику управляющих символов
+	if _, err := ParseControlPolicy(c.ControlChars); err != nil {
+		return err
+	}
+
+	// Проверяем уровень трассировки стека
+	if c.StacktraceLevel != "" {
+		if _, err := ParseLevel(c.StacktraceLevel); err != nil {
+			return fmt.Errorf("некорректный уровень трассировки стека: %s", c.StacktraceLevel)
+		}
+	}
+	return nil
+}
+
+// validatePipeline проверяет маскирование, сэмплирование, ограничение потока и подавление повторов
+func (c *Config) validatePipeline() error {
+	// Проверяем правила маскирования
+	if c.Redact != nil {
+		if _, err := NewRedactor(*c.Redact); err != nil {
+			return err
+		}
 	}
 
+	// Проверяем параметры сэмплирования
+	if c.Sampling != nil && (c.Sampling.Interval < 0 || c.Sampling.Initial < 0 || c.Sampling.Thereafter < 0) {
+		return fmt.Errorf("некорректные параметры сэмплирования: значения не могут быть отрицательными")
+	}
+
+	// Проверяем ограничения потока записей
+	if rl := c.RateLimit; rl != nil && (rl.Records < 0 || rl.Bytes < 0 || rl.RecordsBurst < 0 || rl.BytesBurst < 0) {
+		return fmt.Errorf("некорректные ограничения потока записей: значения не могут быть отрицательными")
+	}
+
+	// Проверяем окно подавления повторов
+	if c.DedupWindow < 0 {
+		return fmt.Errorf("некорректное окно подавления повторов: %s", c.DedupWindow)
+	}
+	return nil
+}
+
+// validateOutputs проверяет фильтры, дополнительные файлы и вывод в stderr
+func (c *Config) validateOutputs() error {
+	// Проверяем правила фильтрации
+	if _, err := NewFilter(c.Filters); err != nil {
+		return err
+	}
+
+	// Проверяем дополнительные файлы
+	for _, sc := range c.FileSinks {
+		if err := sc.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Проверяем уровень вывода в stderr
+	if c.StderrLevel != "" {
+		if _, err := ParseLevel(c.StderrLevel); err != nil {
+			return fmt.Errorf("некорректный уровень вывода в stderr: %s", c.StderrLevel)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"io"
+	"os"
+)
+
+// DefaultStderrLevel уровень, начиная с которого записи выводятся в stderr
+// при разделении консольного вывода
+const DefaultStderrLevel = WarningLevel
+
+// WithStderr создает новый логгер, выводящий в консоль записи уровня level
+// и выше в stderr, а остальные в stdout
+func (l *Logger) WithStderr(level Level) *Logger {
+	newLogger := l.clone()
+	newLogger.stderrLevel = level
+	newLogger.splitStderr = true
+	return newLogger
+}
+
+// WithConsole создает новый логгер с заданными потоками консольного вывода.
+// nil оставляет стандартный поток os.Stdout или os.Stderr
+func (l *Logger) WithConsole(stdout, stderr io.Writer) *Logger {
+	newLogger := l.clone()
+	newLogger.stdout = stdout
+	newLogger.stderr = stderr
+	return newLogger
+}
+
+// console возвращает поток консольного вывода для записи уровня level.
+// Стандартные потоки определяются при каждой записи, чтобы учитывать их подмену
+func (l *Logger) console(level Level) io.Writer {
+	if l.splitStderr && level >= l.stderrLevel {
+		if l.stderr != nil {
+			return l.stderr
+		}
+		return os.Stderr
+	}
+	if l.stdout != nil {
+		return l.stdout
+	}
+	return os.Stdout
+}
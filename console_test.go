@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerConsoleSplit(t *testing.T) {
+	t.Run("без разделения все записи в stdout", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		l := New().WithConsole(&stdout, &stderr)
+		l.Info("info")
+		l.Error("error")
+
+		assert.Equal(t, 2, strings.Count(stdout.String(), "\n"))
+		assert.Empty(t, stderr.String())
+	})
+
+	t.Run("разделение по уровню", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		l := New().WithConsole(&stdout, &stderr).WithStderr(DefaultStderrLevel)
+		l.SetLevel("debug")
+		l.Debug("debug")
+		l.Info("info")
+		l.Warning("warning")
+		l.WithPrefix("DB").Error("error")
+
+		assert.Contains(t, stdout.String(), "[DEBUG] debug")
+		assert.Contains(t, stdout.String(), "[INFO] info")
+		assert.NotContains(t, stdout.String(), "warning")
+		assert.Contains(t, stderr.String(), "[WARNING] warning")
+		assert.Contains(t, stderr.String(), "[ERROR] [DB] error")
+		assert.NotContains(t, stderr.String(), "info")
+	})
+
+	t.Run("настраиваемый порог и общий кодировщик", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		l := New().WithConsole(&stdout, &stderr).WithConfig(&Config{
+			Level:       "info",
+			Format:      FormatJSON,
+			SplitStderr: true,
+			StderrLevel: "error",
+		})
+		l.Warning("warning")
+		l.Error("error")
+
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &decoded))
+		assert.Equal(t, "warning", decoded[MessageKey])
+		require.NoError(t, json.Unmarshal(stderr.Bytes(), &decoded))
+		assert.Equal(t, "error", decoded[MessageKey])
+	})
+
+	t.Run("порог по умолчанию из конфигурации", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		l := New().WithConsole(&stdout, &stderr).WithConfig(&Config{Level: "info", SplitStderr: true})
+		l.Info("info")
+		l.Warning("warning")
+
+		assert.Contains(t, stdout.String(), "info")
+		assert.Contains(t, stderr.String(), "warning")
+		assert.NotContains(t, stdout.String(), "warning")
+	})
+}
+
+func TestConfigValidateStderrLevel(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SplitStderr = true
+	cfg.StderrLevel = "error"
+	require.NoError(t, cfg.Validate())
+
+	cfg.StderrLevel = "loud"
+	require.Error(t, cfg.Validate())
+}
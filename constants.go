@@ -35,3 +35,26 @@ const (
 	DefaultMaxAge     = 28  // дни
 	DefaultFilePerm   = 0644
 )
+
+// Ключи служебных полей записи
+const (
+	TimeKey       = "time"
+	LevelKey      = "level"
+	PrefixKey     = "prefix"
+	MessageKey    = "msg"
+	CallerKey     = "caller"
+	FunctionKey   = "func"
+	StacktraceKey = "stacktrace"
+	ErrorKey      = "error"
+	SampledKey    = "sampled_message"
+	RepeatedKey   = "repeated_message"
+	DroppedKey    = "dropped"
+	TraceIDKey    = "trace_id"
+	SpanIDKey     = "span_id"
+)
+
+// Форматы вывода записей
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDedupWindow окно подавления повторов по умолчанию
+const DefaultDedupWindow = 10 * time.Second
+
+// Deduplicator схлопывает подряд идущие одинаковые записи: первая запись выводится,
+// повторы в пределах окна подавляются, а по окончании серии или окна выводится
+// запись "repeated N times"
+type Deduplicator struct {
+	now    func() time.Time
+	timer  *time.Timer
+	owner  *Logger
+	last   *Record
+	start  time.Time
+	key    string
+	mu     sync.Mutex
+	window time.Duration
+	count  int
+}
+
+// NewDeduplicator создает Deduplicator с указанным окном; window <= 0 означает DefaultDedupWindow
+func NewDeduplicator(window time.Duration) *Deduplicator {
+	if window <= 0 {
+		window = DefaultDedupWindow
+	}
+	return &Deduplicator{window: window, now: time.Now}
+}
+
+// admit решает, выводить ли запись. Повтор предыдущей записи в пределах окна
+// подавляется, иначе перед записью выводится итог предыдущей серии
+func (d *Deduplicator) admit(l *Logger, r *Record) bool {
+	key := dedupKey(r)
+	now := d.now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.last != nil && key == d.key && now.Sub(d.start) < d.window {
+		d.count++
+		d.owner = l
+		if d.timer == nil {
+			// Итог выводится по истечении окна, даже если новых записей не будет
+			d.timer = time.AfterFunc(d.start.Add(d.window).Sub(now), d.expire)
+		}
+		return false
+	}
+
+	d.flushLocked()
+	d.last = r
+	d.key = key
+	d.start = now
+	return true
+}
+
+// expire выводит итог серии по истечении окна
+func (d *Deduplicator) expire() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.timer = nil
+	d.flushLocked()
+	// Следующая такая же запись начнет новую серию и будет выведена
+	d.last = nil
+	d.key = ""
+}
+
+// flush выводит итог текущей серии повторов
+func (d *Deduplicator) flush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.flushLocked()
+	d.last = nil
+	d.key = ""
+}
+
+// flushLocked выводит итог серии, если были подавленные повторы. Вызывается под d.mu
+func (d *Deduplicator) flushLocked() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.count == 0 || d.last == nil {
+		return
+	}
+
+	summary := &Record{
+		Time:    d.now(),
+		Level:   d.last.Level,
+		Prefix:  d.last.Prefix,
+		Message: fmt.Sprintf("repeated %d times", d.count),
+		Fields:  map[string]interface{}{RepeatedKey: d.last.Message},
+	}
+	d.count = 0
+	d.owner.write(summary)
+}
+
+// dedupKey формирует ключ сравнения записей из уровня, префикса, сообщения и полей
+func dedupKey(r *Record) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d\x00%s\x00%s", r.Level, r.Prefix, r.Message)
+	for _, k := range sortedKeys(r.Fields) {
+		fmt.Fprintf(&b, "\x00%s=%v", k, r.Fields[k])
+	}
+	return b.String()
+}
+
+// WithDeduplicator создает новый логгер, подавляющий подряд идущие одинаковые записи.
+// Deduplicator разделяется между логгером и всеми его потомками
+func (l *Logger) WithDeduplicator(d *Deduplicator) *Logger {
+	newLogger := l.clone()
+	newLogger.dedup = d
+	return newLogger
+}
@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeduplicator(t *testing.T) {
+	d := NewDeduplicator(time.Minute)
+	l := New().WithDeduplicator(d)
+
+	for i := 0; i < 5; i++ {
+		l.Errorf("connect failed: %s", "refused")
+	}
+	l.Info("connected")
+
+	messages := l.GetMessages()
+	require.Len(t, messages, 3)
+	assert.Contains(t, messages[0], "[ERROR] connect failed: refused")
+	assert.Contains(t, messages[1], "[ERROR] repeated 4 times [repeated_message=connect failed: refused]")
+	assert.Contains(t, messages[2], "[INFO] connected")
+}
+
+func TestDeduplicatorDistinguishesFieldsAndPrefix(t *testing.T) {
+	l := New().WithDeduplicator(NewDeduplicator(time.Minute))
+	a := l.WithFields(map[string]interface{}{"attempt": 1})
+	b := l.WithFields(map[string]interface{}{"attempt": 2})
+	p := l.WithPrefix("DB")
+
+	a.Warning("retry")
+	b.Warning("retry")
+	p.Warning("retry")
+
+	assert.Len(t, a.GetMessages(), 1)
+	assert.Len(t, b.GetMessages(), 1)
+	assert.Len(t, p.GetMessages(), 1)
+}
+
+func TestDeduplicatorWindowExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC)}
+	d := NewDeduplicator(time.Hour)
+	d.now = clock.Now
+	l := New().WithDeduplicator(d)
+
+	l.Info("tick")
+	l.Info("tick")
+	clock.Advance(2 * time.Hour)
+	l.Info("tick")
+
+	messages := l.GetMessages()
+	require.Len(t, messages, 3)
+	assert.Contains(t, messages[1], "[INFO] repeated 1 times")
+	assert.Contains(t, messages[2], "[INFO] tick")
+}
+
+func TestDeduplicatorTimer(t *testing.T) {
+	l := New().WithDeduplicator(NewDeduplicator(50 * time.Millisecond))
+
+	l.Info("tick")
+	l.Info("tick")
+	l.Info("tick")
+
+	assert.Eventually(t, func() bool {
+		return countContaining(l.GetMessages(), "repeated 2 times") == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// После истечения окна такая же запись снова выводится
+	l.Info("tick")
+	assert.Equal(t, 2, countContaining(l.GetMessages(), "[INFO] tick"))
+}
+
+func TestDeduplicatorFlushOnClose(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DedupWindow = time.Hour
+	require.NoError(t, cfg.Validate())
+
+	l := New().WithConfig(cfg)
+	l.Error("disk full")
+	l.Error("disk full")
+	require.NoError(t, l.Close())
+
+	messages := l.GetMessages()
+	require.Len(t, messages, 2)
+	assert.Contains(t, messages[1], "[ERROR] repeated 1 times [repeated_message=disk full]")
+
+	cfg.DedupWindow = -time.Second
+	require.Error(t, cfg.Validate())
+}
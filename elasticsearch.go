@@ -0,0 +1,272 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Параметры назначения Elasticsearch по умолчанию
+const (
+	DefaultElasticsearchIndex      = "logs-{date}"
+	DefaultElasticsearchDateFormat = "2006.01.02"
+)
+
+// ElasticsearchConfig параметры назначения Elasticsearch или OpenSearch
+type ElasticsearchConfig struct {
+	BatchConfig
+	RetryConfig
+	// Client HTTP-клиент, по умолчанию клиент с таймаутом DefaultHTTPTimeout
+	Client *http.Client
+	// Headers дополнительные заголовки запросов
+	Headers map[string]string
+	// Encoder кодировщик документов, формирующий JSON-объект, по умолчанию ECSEncoder
+	Encoder Encoder
+	// URL адрес кластера, например http://localhost:9200
+	URL string
+	// Index шаблон имени индекса, по умолчанию DefaultElasticsearchIndex.
+	// {date} заменяется датой записи в UTC, {prefix} префиксом записи в нижнем регистре
+	Index string
+	// DateFormat формат даты для {date}, по умолчанию DefaultElasticsearchDateFormat
+	DateFormat string
+	// Username и Password данные базовой аутентификации
+	Username string
+	Password string
+	// APIKey ключ API, передаваемый в заголовке Authorization: ApiKey
+	APIKey string
+	// DeadLetterFile файл для документов, отвергнутых кластером; пустое значение отключает запись
+	DeadLetterFile string
+}
+
+// ElasticsearchSink индексирует записи через _bulk API. Ответ разбирается
+// по отдельным документам: документы с временными ошибками (429, 5xx) отправляются
+// повторно, остальные отвергнутые документы записываются в файл недоставленных
+type ElasticsearchSink struct {
+	*batchSender
+	cfg        ElasticsearchConfig
+	deadLetter *os.File
+	mu         sync.Mutex
+}
+
+// bulkResponse ответ _bulk API
+type bulkResponse struct {
+	Items  []map[string]bulkItem `json:"items"`
+	Errors bool                  `json:"errors"`
+}
+
+// bulkItem результат операции с одним документом
+type bulkItem struct {
+	Error  json.RawMessage `json:"error"`
+	Index  string          `json:"_index"`
+	Status int             `json:"status"`
+}
+
+// deadLetter запись файла недоставленных документов
+type deadLetter struct {
+	Time     time.Time       `json:"time"`
+	Index    string          `json:"index"`
+	Error    json.RawMessage `json:"error,omitempty"`
+	Document json.RawMessage `json:"document"`
+	Status   int             `json:"status"`
+}
+
+// NewElasticsearchSink создает назначение Elasticsearch
+func NewElasticsearchSink(cfg ElasticsearchConfig) (*ElasticsearchSink, error) {
+	if cfg.URL == "" {
+		return nil, &ConfigError{Err: fmt.Errorf("не указан URL"), Reason: "некорректное назначение Elasticsearch"}
+	}
+	if cfg.Encoder == nil {
+		cfg.Encoder = ECSEncoder{}
+	}
+	if cfg.Index == "" {
+		cfg.Index = DefaultElasticsearchIndex
+	}
+	if cfg.DateFormat == "" {
+		cfg.DateFormat = DefaultElasticsearchDateFormat
+	}
+
+	s := &ElasticsearchSink{cfg: cfg}
+	if cfg.DeadLetterFile != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.DeadLetterFile), 0750); err != nil {
+			return nil, &WriteError{Message: "не удалось создать каталог для " + cfg.DeadLetterFile, Cause: err}
+		}
+		f, err := os.OpenFile(filepath.Clean(cfg.DeadLetterFile), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, &WriteError{Message: "не удалось открыть файл " + cfg.DeadLetterFile, Cause: err}
+		}
+		s.deadLetter = f
+	}
+
+	s.batchSender = newBatchSender("elasticsearch", cfg.BatchConfig, cfg.Client, cfg.RetryConfig, s.request)
+	s.response = s.handleResponse
+	return s, nil
+}
+
+// Write реализует интерфейс Sink
+func (s *ElasticsearchSink) Write(r *Record) error {
+	return s.add(batchEntry{record: r, data: []byte(s.cfg.Encoder.Encode(r))})
+}
+
+// index возвращает имя индекса для записи
+func (s *ElasticsearchSink) index(r *Record) string {
+	return strings.NewReplacer(
+		"{date}", r.Time.UTC().Format(s.cfg.DateFormat),
+		"{prefix}", strings.ToLower(r.Prefix),
+	).Replace(s.cfg.Index)
+}
+
+// request строит запрос _bulk с операцией create для каждого документа
+func (s *ElasticsearchSink) request(batch []batchEntry) (func() (*http.Request, error), error) {
+	var body bytes.Buffer
+	for _, e := range batch {
+		body.WriteString(`{"create":{"_index":`)
+		body.Write(marshalJSON(s.index(e.record)))
+		body.WriteString("}}\n")
+		body.Write(e.data)
+		body.WriteByte('\n')
+	}
+
+	headers := make(map[string]string, len(s.cfg.Headers)+1)
+	for k, v := range s.cfg.Headers {
+		headers[k] = v
+	}
+	if s.cfg.APIKey != "" {
+		headers["Authorization"] = "ApiKey " + s.cfg.APIKey
+	}
+	build, err := newBodyRequest(http.MethodPost, strings.TrimSuffix(s.cfg.URL, "/")+"/_bulk", "application/x-ndjson", headers, body.Bytes(), false)
+	if err != nil || s.cfg.Username == "" {
+		return build, err
+	}
+	return func() (*http.Request, error) {
+		req, err := build()
+		if err == nil {
+			req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+		}
+		return req, err
+	}, nil
+}
+
+// handleResponse разбирает результаты по документам. Документы с временными
+// ошибками отправляются повторно с паузой, как и весь запрос; отвергнутые
+// окончательно документы записываются в файл недоставленных
+func (s *ElasticsearchSink) handleResponse(body []byte, batch []batchEntry) (int, error) {
+	backoff := s.poster.retry.MinBackoff
+	rejected := 0
+	for attempt := 0; ; attempt++ {
+		var resp bulkResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return s.failRetry(attempt, rejected, batch, fmt.Errorf("некорректный ответ _bulk: %w", err))
+		}
+		if !resp.Errors {
+			return rejected, nil
+		}
+		if len(resp.Items) != len(batch) {
+			return s.failRetry(attempt, rejected, batch,
+				fmt.Errorf("ответ _bulk содержит %d результатов вместо %d", len(resp.Items), len(batch)))
+		}
+
+		var retry []batchEntry
+		for i, item := range resp.Items {
+			result := bulkResultOf(item)
+			switch {
+			case result.Status < 300:
+			case retryable(result.Status) && attempt < s.poster.retry.MaxRetries:
+				retry = append(retry, batch[i])
+			default:
+				rejected++
+				s.writeDeadLetter(batch[i], result)
+			}
+		}
+		if len(retry) == 0 {
+			return rejected, nil
+		}
+
+		if !s.poster.sleep(backoff) {
+			return s.failRetry(attempt+1, rejected, retry, errRetriesAborted)
+		}
+		if backoff *= 2; backoff > s.poster.retry.MaxBackoff {
+			backoff = s.poster.retry.MaxBackoff
+		}
+		build, err := s.request(retry)
+		if err == nil {
+			body, err = s.poster.do(build)
+		}
+		if err != nil {
+			return s.failRetry(attempt+1, rejected, retry, err)
+		}
+		batch = retry
+	}
+}
+
+// failRetry завершает разбор ответа ошибкой. Ответ на первый запрос относится
+// ко всему пакету, который учитывает отправитель; документы повторных попыток
+// считаются отвергнутыми и записываются в файл недоставленных
+func (s *ElasticsearchSink) failRetry(attempt, rejected int, batch []batchEntry, err error) (int, error) {
+	if attempt == 0 {
+		return 0, err
+	}
+	reason, _ := json.Marshal(err.Error())
+	for _, e := range batch {
+		s.writeDeadLetter(e, bulkItem{Error: reason})
+	}
+	return rejected + len(batch), err
+}
+
+// bulkResultOf извлекает результат операции независимо от ее типа
+func bulkResultOf(item map[string]bulkItem) bulkItem {
+	for _, result := range item {
+		return result
+	}
+	return bulkItem{}
+}
+
+// writeDeadLetter записывает отвергнутый документ с причиной отказа
+func (s *ElasticsearchSink) writeDeadLetter(e batchEntry, result bulkItem) {
+	index := result.Index
+	if index == "" {
+		index = s.index(e.record)
+	}
+	line, err := json.Marshal(deadLetter{
+		Time:     time.Now(),
+		Index:    index,
+		Status:   result.Status,
+		Error:    result.Error,
+		Document: json.RawMessage(e.data),
+	})
+	if err != nil {
+		reportSinkError("elasticsearch", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deadLetter == nil {
+		return
+	}
+	if _, err := s.deadLetter.Write(append(line, '\n')); err != nil {
+		reportSinkError("elasticsearch", err)
+	}
+}
+
+// Close реализует интерфейс Sink
+func (s *ElasticsearchSink) Close() error {
+	// Файл недоставленных закрывается и при ошибке отправки накопленных записей
+	err := s.batchSender.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deadLetter == nil {
+		return err
+	}
+	if closeErr := s.deadLetter.Close(); err == nil {
+		err = closeErr
+	}
+	s.deadLetter = nil
+	return err
+}
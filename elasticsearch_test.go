@@ -0,0 +1,225 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bulkServer фейковый _bulk API. reject возвращает код ответа для документа
+// по его сообщению и номеру попытки; 0 означает успешную запись
+type bulkServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	docs    []map[string]interface{}
+	indexes []string
+	auth    string
+	reject  func(msg string, attempt int) int
+	tries   map[string]int
+}
+
+func newBulkServer(t *testing.T, reject func(msg string, attempt int) int) *bulkServer {
+	s := &bulkServer{reject: reject, tries: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.auth = r.Header.Get("Authorization")
+
+		var items []string
+		hasErrors := false
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var action map[string]map[string]string
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &action))
+			require.True(t, scanner.Scan())
+			var doc map[string]interface{}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &doc))
+
+			index := action["create"]["_index"]
+			msg, _ := doc["message"].(string)
+			status := 0
+			if s.reject != nil {
+				status = s.reject(msg, s.tries[msg])
+			}
+			s.tries[msg]++
+			if status == 0 {
+				s.docs = append(s.docs, doc)
+				s.indexes = append(s.indexes, index)
+				items = append(items, `{"create":{"_index":"`+index+`","status":201}}`)
+				continue
+			}
+			hasErrors = true
+			items = append(items, `{"create":{"_index":"`+index+`","status":`+itoa(status)+`,"error":{"type":"mapper_parsing_exception","reason":"bad"}}}`)
+		}
+		_, _ = io.WriteString(w, `{"took":1,"errors":`+map[bool]string{true: "true", false: "false"}[hasErrors]+`,"items":[`+strings.Join(items, ",")+`]}`)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func itoa(n int) string {
+	b, _ := json.Marshal(n)
+	return string(b)
+}
+
+func TestElasticsearchSinkIndexesDocuments(t *testing.T) {
+	srv := newBulkServer(t, nil)
+	sink, err := NewElasticsearchSink(ElasticsearchConfig{
+		URL:    srv.URL + "/",
+		Index:  "logs-{prefix}-{date}",
+		APIKey: "secret",
+	})
+	require.NoError(t, err)
+
+	ts := time.Date(2024, 3, 5, 23, 30, 0, 0, time.UTC)
+	require.NoError(t, sink.Write(&Record{
+		Time:    ts,
+		Level:   ErrorLevel,
+		Prefix:  "API",
+		Message: "failed",
+		Caller:  &Caller{File: "app/main.go", Line: 10, Function: "main.run"},
+		Fields:  map[string]interface{}{ErrorKey: errors.New("boom"), "user": "bob", "message": "dup"},
+		Stack:   "main.run\n\tapp/main.go:10",
+	}))
+	require.NoError(t, sink.Close())
+
+	require.Len(t, srv.docs, 1)
+	assert.Equal(t, "logs-api-2024.03.05", srv.indexes[0])
+	assert.Equal(t, "ApiKey secret", srv.auth)
+
+	doc := srv.docs[0]
+	assert.Equal(t, "2024-03-05T23:30:00Z", doc["@timestamp"])
+	assert.Equal(t, "error", doc["log.level"])
+	assert.Equal(t, "API", doc["log.logger"])
+	assert.Equal(t, "failed", doc["message"])
+	assert.Equal(t, "app/main.go", doc["log.origin.file.name"])
+	assert.Equal(t, float64(10), doc["log.origin.file.line"])
+	assert.Equal(t, "boom", doc["error.message"])
+	assert.Equal(t, "*errors.errorString", doc["error.type"])
+	assert.Equal(t, "main.run\n\tapp/main.go:10", doc["error.stack_trace"])
+	assert.Equal(t, ECSVersion, doc["ecs.version"])
+	assert.Equal(t, "bob", doc["user"])
+	assert.Equal(t, "dup", doc["labels.message"])
+	assert.Equal(t, uint64(1), sink.Stats().SentRecords)
+}
+
+func TestElasticsearchSinkPartialFailures(t *testing.T) {
+	srv := newBulkServer(t, func(msg string, attempt int) int {
+		switch {
+		case msg == "rejected":
+			return http.StatusBadRequest
+		case msg == "throttled" && attempt == 0:
+			return http.StatusTooManyRequests
+		}
+		return 0
+	})
+	deadLetters := filepath.Join(t.TempDir(), "dlq", "rejected.ndjson")
+	sink, err := NewElasticsearchSink(ElasticsearchConfig{
+		URL:            srv.URL,
+		Username:       "elastic",
+		Password:       "changeme",
+		DeadLetterFile: deadLetters,
+		RetryConfig:    RetryConfig{MinBackoff: time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	for _, msg := range []string{"ok", "rejected", "throttled"} {
+		require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: InfoLevel, Message: msg}))
+	}
+	// Close сообщает об отвергнутом документе и все равно закрывает файл недоставленных
+	assert.Error(t, sink.Close())
+	assert.Nil(t, sink.deadLetter)
+
+	var stored []string
+	for _, doc := range srv.docs {
+		stored = append(stored, doc["message"].(string))
+	}
+	assert.Equal(t, []string{"ok", "throttled"}, stored)
+	assert.True(t, strings.HasPrefix(srv.auth, "Basic "))
+
+	stats := sink.Stats()
+	assert.Equal(t, uint64(2), stats.SentRecords)
+	assert.Equal(t, uint64(1), stats.DroppedRecords)
+
+	data, err := os.ReadFile(deadLetters)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+
+	var letter struct {
+		Index    string                 `json:"index"`
+		Error    map[string]string      `json:"error"`
+		Document map[string]interface{} `json:"document"`
+		Status   int                    `json:"status"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &letter))
+	assert.Equal(t, http.StatusBadRequest, letter.Status)
+	assert.Equal(t, "mapper_parsing_exception", letter.Error["type"])
+	assert.Equal(t, "rejected", letter.Document["message"])
+	assert.True(t, strings.HasPrefix(letter.Index, "logs-"))
+}
+
+func TestElasticsearchSinkDeadLettersFailedRetry(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			// повторная отправка отвергнутого подмножества завершается ошибкой запроса
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = io.WriteString(w, `{"errors":true,"items":[{"create":{"status":201}},{"create":{"status":429}}]}`)
+	}))
+	t.Cleanup(srv.Close)
+	deadLetters := filepath.Join(t.TempDir(), "rejected.ndjson")
+	sink, err := NewElasticsearchSink(ElasticsearchConfig{
+		URL:            srv.URL,
+		DeadLetterFile: deadLetters,
+		RetryConfig:    RetryConfig{MinBackoff: time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	for _, msg := range []string{"ok", "throttled"} {
+		require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: InfoLevel, Message: msg}))
+	}
+	assert.Error(t, sink.Close())
+
+	stats := sink.Stats()
+	assert.Equal(t, uint64(1), stats.SentRecords)
+	assert.Equal(t, uint64(1), stats.DroppedRecords)
+
+	data, err := os.ReadFile(deadLetters)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+
+	var letter struct {
+		Error    string                 `json:"error"`
+		Document map[string]interface{} `json:"document"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &letter))
+	assert.Equal(t, "throttled", letter.Document["message"])
+	assert.Contains(t, letter.Error, "400")
+}
+
+func TestElasticsearchSinkConfig(t *testing.T) {
+	_, err := NewElasticsearchSink(ElasticsearchConfig{})
+	var cfgErr *ConfigError
+	assert.ErrorAs(t, err, &cfgErr)
+}
@@ -0,0 +1,285 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Encoder преобразует запись лога в строку для вывода
+type Encoder interface {
+	Encode(r *Record) string
+}
+
+// TextEncoder кодирует записи в текстовый формат вида
+// "2006-01-02 15:04:05 [INFO] [PREFIX] сообщение [key=value]"
+type TextEncoder struct {
+	// TimeFormat формат времени, по умолчанию DefaultTimeFormat
+	TimeFormat string
+	// Control политика обработки управляющих символов в префиксе, сообщении и полях,
+	// по умолчанию ControlEscape
+	Control ControlPolicy
+}
+
+// Encode реализует интерфейс Encoder
+func (e TextEncoder) Encode(r *Record) string {
+	timeFormat := DefaultTimeFormat
+	if e.TimeFormat != "" {
+		timeFormat = e.TimeFormat
+	}
+
+	var b strings.Builder
+	b.WriteString(r.Time.Format(timeFormat))
+	b.WriteByte(' ')
+	b.WriteString(getLevelString(r.Level))
+	b.WriteByte(' ')
+	if r.Prefix != "" {
+		b.WriteString("[" + e.Control.sanitize(r.Prefix) + "] ")
+	}
+	b.WriteString(e.Control.sanitize(r.Message))
+
+	// Формируем строку с полями: сначала служебные, затем пользовательские по алфавиту.
+	// Ошибки разворачиваются в плоский набор ключей с типом и цепочкой причин
+	var fields []string
+	for _, pair := range flatFields(r) {
+		fields = append(fields, e.Control.sanitize(pair.Key+"="+pair.Value))
+	}
+	if len(fields) > 0 {
+		b.WriteString(" [" + strings.Join(fields, " ") + "]")
+	}
+
+	// Трассировка стека выводится отдельными строками после записи. Каждая строка
+	// начинается с табуляции, поэтому не может быть принята за начало новой записи
+	if r.Stack != "" {
+		for _, line := range strings.Split(r.Stack, "\n") {
+			b.WriteByte('\n')
+			if e.Control == ControlRaw {
+				b.WriteString(line)
+				continue
+			}
+			b.WriteByte('\t')
+			b.WriteString(e.Control.sanitize(line))
+		}
+	}
+
+	return b.String()
+}
+
+// JSONEncoder кодирует записи в JSON-объект, по одному объекту на строку
+type JSONEncoder struct {
+	// TimeFormat формат времени, по умолчанию time.RFC3339Nano
+	TimeFormat string
+}
+
+// Encode реализует интерфейс Encoder
+func (e JSONEncoder) Encode(r *Record) string {
+	timeFormat := time.RFC3339Nano
+	if e.TimeFormat != "" {
+		timeFormat = e.TimeFormat
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	writeJSONField(&b, TimeKey, r.Time.Format(timeFormat), true)
+	writeJSONField(&b, LevelKey, r.Level.String(), false)
+	if r.Prefix != "" {
+		writeJSONField(&b, PrefixKey, r.Prefix, false)
+	}
+	writeJSONField(&b, MessageKey, r.Message, false)
+	if r.Caller != nil {
+		writeJSONField(&b, CallerKey, r.Caller.String(), false)
+		if r.Caller.Function != "" {
+			writeJSONField(&b, FunctionKey, r.Caller.Function, false)
+		}
+	}
+	for _, k := range sortedKeys(r.Fields) {
+		writeJSONField(&b, jsonFieldKey(k), r.Fields[k], false)
+	}
+	if r.Stack != "" {
+		writeJSONField(&b, StacktraceKey, r.Stack, false)
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// jsonFieldKey возвращает ключ пользовательского поля. Поля, совпадающие со служебными
+// ключами, получают префикс "fields.", чтобы в объекте не было повторяющихся ключей
+func jsonFieldKey(key string) string {
+	switch key {
+	case TimeKey, LevelKey, PrefixKey, MessageKey, CallerKey, FunctionKey, StacktraceKey:
+		return "fields." + key
+	}
+	return key
+}
+
+// writeJSONField добавляет в буфер пару "ключ":значение
+func writeJSONField(b *bytes.Buffer, key string, value interface{}, first bool) {
+	if !first {
+		b.WriteByte(',')
+	}
+	b.Write(marshalJSON(key))
+	b.WriteByte(':')
+	b.Write(marshalJSON(value))
+}
+
+// marshalJSON сериализует значение в JSON без экранирования HTML.
+// Ошибки сериализуются вложенным объектом, значения, которые не удается
+// сериализовать, записываются строкой
+func marshalJSON(v interface{}) []byte {
+	if err, ok := v.(error); ok && err != nil {
+		v = describeError(err, 0)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		buf.Reset()
+		_ = enc.Encode(fmt.Sprint(v))
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// plainValue приводит значение поля к одному из простых типов: nil, bool, string,
+// []byte, int64, uint64, float64, []interface{} или map[string]interface{}.
+// Сложные типы и ошибки проходят через JSON, чтобы представление совпадало
+// с JSONEncoder. Элементы коллекций не преобразуются
+func plainValue(v interface{}) interface{} {
+	if n, ok := plainNumber(v); ok {
+		return n
+	}
+	switch v := v.(type) {
+	case nil, bool, string, []byte, []interface{}, map[string]interface{}:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	case error:
+	case fmt.Stringer:
+		return v.String()
+	}
+	return plainJSON(v)
+}
+
+// plainNumber приводит целые числа к int64 или uint64, а дробные к float64.
+// json.Number, не являющийся числом, возвращается строкой
+func plainNumber(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64, uint64, float64:
+		return v, true
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case uint:
+		return uint64(v), true
+	case uint8:
+		return uint64(v), true
+	case uint16:
+		return uint64(v), true
+	case uint32:
+		return uint64(v), true
+	case float32:
+		return float64(v), true
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, true
+		}
+		if f, err := v.Float64(); err == nil {
+			return f, true
+		}
+		return v.String(), true
+	}
+	return nil, false
+}
+
+// plainJSON приводит сложное значение или ошибку к простым типам через JSON
+func plainJSON(v interface{}) interface{} {
+	var decoded interface{}
+	dec := json.NewDecoder(bytes.NewReader(marshalJSON(v)))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		return fmt.Sprint(v)
+	}
+	if n, ok := decoded.(json.Number); ok {
+		return plainValue(n)
+	}
+	return decoded
+}
+
+// sortedKeys возвращает ключи полей в алфавитном порядке
+func sortedKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ECSVersion версия Elastic Common Schema, которой соответствует ECSEncoder
+const ECSVersion = "8.11.0"
+
+// ECSEncoder кодирует записи в JSON в соответствии с Elastic Common Schema:
+// @timestamp, log.level, log.logger, log.origin.*, message, error.*.
+// Пользовательские поля записываются на верхнем уровне; поле ошибки ErrorKey
+// отображается в error.message и error.type
+type ECSEncoder struct{}
+
+// Encode реализует интерфейс Encoder
+func (ECSEncoder) Encode(r *Record) string {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	written := map[string]bool{}
+	field := func(key string, value interface{}) {
+		writeJSONField(&b, key, value, len(written) == 0)
+		written[key] = true
+	}
+
+	field("@timestamp", r.Time.UTC().Format(time.RFC3339Nano))
+	field("log.level", strings.ToLower(r.Level.String()))
+	if r.Prefix != "" {
+		field("log.logger", r.Prefix)
+	}
+	field("message", r.Message)
+	if r.Caller != nil {
+		field("log.origin.file.name", r.Caller.File)
+		field("log.origin.file.line", r.Caller.Line)
+		if r.Caller.Function != "" {
+			field("log.origin.function", r.Caller.Function)
+		}
+	}
+	if err, ok := r.Fields[ErrorKey].(error); ok && err != nil {
+		info := describeError(err, 0)
+		field("error.message", info.Message)
+		field("error.type", info.Type)
+	}
+	if r.Stack != "" {
+		field("error.stack_trace", r.Stack)
+	}
+	field("ecs.version", ECSVersion)
+
+	for _, k := range sortedKeys(r.Fields) {
+		if _, ok := r.Fields[k].(error); ok && k == ErrorKey {
+			continue
+		}
+		// Поля, совпадающие со служебными, переносятся в labels, чтобы не дублировать ключи
+		key := k
+		if written[key] {
+			key = "labels." + k
+		}
+		field(key, r.Fields[k])
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
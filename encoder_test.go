@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextEncoder(t *testing.T) {
+	record := &Record{
+		Time:    time.Date(2024, 1, 30, 10, 20, 30, 0, time.UTC),
+		Level:   WarningLevel,
+		Prefix:  "API",
+		Message: "request failed",
+		Fields:  map[string]interface{}{"b": 2, "a": "x"},
+		Caller:  &Caller{File: "/src/app/handler.go", Line: 42, Function: "app.Handle"},
+	}
+
+	assert.Equal(t,
+		"2024-01-30 10:20:30 [WARNING] [API] request failed [caller=app/handler.go:42 func=app.Handle a=x b=2]",
+		TextEncoder{}.Encode(record),
+	)
+
+	record.Caller = nil
+	assert.Equal(t,
+		"2024-01-30T10:20:30Z [WARNING] [API] request failed [a=x b=2]",
+		TextEncoder{TimeFormat: time.RFC3339}.Encode(record),
+	)
+}
+
+func TestJSONEncoder(t *testing.T) {
+	record := &Record{
+		Time:    time.Date(2024, 1, 30, 10, 20, 30, 0, time.UTC),
+		Level:   ErrorLevel,
+		Prefix:  "DB",
+		Message: "query <failed>",
+		Fields: map[string]interface{}{
+			"attempt": 3,
+			"err":     errors.New("timeout"),
+		},
+		Caller: &Caller{File: "/src/app/db.go", Line: 7, Function: "app.Query"},
+	}
+
+	line := JSONEncoder{}.Encode(record)
+	assert.Equal(t,
+		`{"time":"2024-01-30T10:20:30Z","level":"ERROR","prefix":"DB","msg":"query <failed>",`+
+			`"caller":"app/db.go:7","func":"app.Query","attempt":3,"err":{"message":"timeout","type":"*errors.errorString"}}`,
+		line,
+	)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(line), &decoded))
+}
+
+func TestLoggerWithConfigFormat(t *testing.T) {
+	l := New().WithConfig(&Config{Level: "debug", Format: FormatJSON, Caller: true})
+	l.WithFields(map[string]interface{}{"key": "value"}).Info("json message")
+	l.Debug("direct")
+
+	messages := l.GetMessages()
+	require.Len(t, messages, 1)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(messages[0]), &decoded))
+	assert.Equal(t, "DEBUG", decoded[LevelKey])
+	assert.Equal(t, "direct", decoded[MessageKey])
+	assert.Contains(t, decoded[CallerKey], "/encoder_test.go:")
+}
+
+func TestJSONEncoderReservedFieldKeys(t *testing.T) {
+	record := &Record{
+		Time:    time.Date(2024, 1, 30, 10, 20, 30, 0, time.UTC),
+		Level:   InfoLevel,
+		Message: "message",
+		Fields:  map[string]interface{}{"level": "user", MessageKey: "shadow", "other": 1},
+		Caller:  &Caller{File: "/src/app/db.go", Line: 7},
+	}
+
+	line := JSONEncoder{}.Encode(record)
+	assert.Equal(t,
+		`{"time":"2024-01-30T10:20:30Z","level":"INFO","msg":"message","caller":"app/db.go:7",`+
+			`"fields.level":"user","fields.msg":"shadow","other":1}`,
+		line,
+	)
+}
+
+func TestLoggerJSONTimeFormatAfterConfig(t *testing.T) {
+	l := New().WithConfig(&Config{Level: "info", Format: FormatJSON}).WithTimeFormat("2006")
+	l.Info("message")
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(l.GetMessages()[0]), &decoded))
+	assert.Equal(t, time.Now().Format("2006"), decoded[TimeKey])
+}
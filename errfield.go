@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// maxErrorDepth ограничивает глубину разбора цепочки ошибок
+const maxErrorDepth = 16
+
+// LogFielder реализуется ошибками, которые несут собственные структурированные поля.
+// Поля выводятся вместе с сообщением и типом ошибки
+type LogFielder interface {
+	LogFields() map[string]interface{}
+}
+
+// errorInfo структурированное представление ошибки для кодировщиков
+type errorInfo struct {
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+	Message string                 `json:"message"`
+	Type    string                 `json:"type"`
+	Causes  []errorInfo            `json:"causes,omitempty"`
+}
+
+// Err возвращает поле с ошибкой для использования в WithFields.
+// Ошибка выводится с сообщением, типом, цепочкой причин и собственными полями
+func Err(err error) Fields {
+	return Fields{ErrorKey: err}
+}
+
+// Errorw логирует сообщение на уровне ERROR с дополнительными полями,
+// переданными парами ключ-значение: Errorw("запрос не выполнен", "error", err, "attempt", 3)
+func (l *Logger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.logw(ErrorLevel, msg, keysAndValues...)
+}
+
+// logw логирует сообщение с полями логгера, дополненными парами ключ-значение
+func (l *Logger) logw(level Level, msg string, keysAndValues ...interface{}) {
+	if level < l.level {
+		return
+	}
+	if !l.sample(level, msg) {
+		l.countDropped(l.prefix, DropSampled)
+		return
+	}
+
+	fields := copyFields(l.fields)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		if i+1 == len(keysAndValues) {
+			// Значение без пары сохраняем под служебным ключом, чтобы не потерять его
+			fields["!BADKEY"] = keysAndValues[i]
+			break
+		}
+		fields[key] = keysAndValues[i+1]
+	}
+	l.output(level, msg, fields)
+}
+
+// describeError разбирает ошибку и всю цепочку ее причин,
+// включая ошибки, объединенные через Unwrap() []error
+func describeError(err error, depth int) errorInfo {
+	if nilError(err) {
+		return errorInfo{Message: "<nil>", Type: fmt.Sprintf("%T", err)}
+	}
+	if redacted, ok := err.(*redactedError); ok {
+		return redacted.info
+	}
+	info := errorInfo{
+		Message: errorMessage(err),
+		Type:    fmt.Sprintf("%T", err),
+	}
+	if f, ok := err.(LogFielder); ok {
+		info.Fields = f.LogFields()
+	}
+	if depth >= maxErrorDepth {
+		return info
+	}
+	for _, cause := range unwrapAll(err) {
+		info.Causes = append(info.Causes, describeError(cause, depth+1))
+	}
+	return info
+}
+
+// nilError сообщает, что ошибка является типизированным nil-указателем,
+// например var e *ConfigError, сохраненным в интерфейсе error
+func nilError(err error) bool {
+	v := reflect.ValueOf(err)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// errorMessage возвращает сообщение ошибки. Как и fmt, перехватывает панику
+// в методе Error и возвращает ее описание вместо сообщения
+func errorMessage(err error) (msg string) {
+	if nilError(err) {
+		return "<nil>"
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			msg = fmt.Sprintf("%v", err)
+		}
+	}()
+	return err.Error()
+}
+
+// unwrapAll возвращает непосредственные причины ошибки
+func unwrapAll(err error) []error {
+	if nilError(err) {
+		return nil
+	}
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		var causes []error
+		for _, cause := range e.Unwrap() {
+			if cause != nil {
+				causes = append(causes, cause)
+			}
+		}
+		return causes
+	case interface{ Unwrap() error }:
+		if cause := e.Unwrap(); cause != nil {
+			return []error{cause}
+		}
+	}
+	return nil
+}
+
+// flatten разворачивает описание ошибки в плоский список пар для текстовых форматов:
+// error=сообщение error.type=тип error.fields.ключ=значение error.causes.0=...
+func (e errorInfo) flatten(key string) []fieldPair {
+	pairs := []fieldPair{
+		{Key: key, Value: e.Message},
+		{Key: key + ".type", Value: e.Type},
+	}
+	for _, k := range sortedKeys(e.Fields) {
+		pairs = append(pairs, fieldPair{Key: key + ".fields." + k, Value: fmt.Sprint(e.Fields[k])})
+	}
+	for i, cause := range e.Causes {
+		pairs = append(pairs, cause.flatten(key+".causes."+strconv.Itoa(i))...)
+	}
+	return pairs
+}
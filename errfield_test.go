@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// multiError объединяет несколько ошибок аналогично errors.Join
+type multiError struct {
+	errs []error
+}
+
+func (e *multiError) Error() string {
+	parts := make([]string, 0, len(e.errs))
+	for _, err := range e.errs {
+		parts = append(parts, err.Error())
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e *multiError) Unwrap() []error { return e.errs }
+
+func TestDescribeError(t *testing.T) {
+	base := errors.New("disk full")
+	cfgErr := &ConfigError{Err: base, Reason: "bad path"}
+	err := fmt.Errorf("init: %w", &multiError{errs: []error{cfgErr, errors.New("timeout")}})
+
+	info := describeError(err, 0)
+	assert.Equal(t, err.Error(), info.Message)
+	assert.Equal(t, "*fmt.wrapError", info.Type)
+	require.Len(t, info.Causes, 1)
+
+	joined := info.Causes[0]
+	assert.Equal(t, "*logger.multiError", joined.Type)
+	require.Len(t, joined.Causes, 2)
+	assert.Equal(t, "*logger.ConfigError", joined.Causes[0].Type)
+	assert.Equal(t, map[string]interface{}{"reason": "bad path"}, joined.Causes[0].Fields)
+	require.Len(t, joined.Causes[0].Causes, 1)
+	assert.Equal(t, "disk full", joined.Causes[0].Causes[0].Message)
+	assert.Equal(t, "timeout", joined.Causes[1].Message)
+}
+
+func TestErrorFieldText(t *testing.T) {
+	l := New()
+	err := fmt.Errorf("save: %w", &WriteError{Message: "short write"})
+	child := l.WithFields(Err(err))
+	child.Error("failed")
+
+	messages := child.GetMessages()
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0],
+		"[error=save: ошибка записи лога: short write error.type=*fmt.wrapError "+
+			"error.causes.0=ошибка записи лога: short write error.causes.0.type=*logger.WriteError "+
+			"error.causes.0.fields.message=short write]")
+}
+
+func TestErrorFieldJSON(t *testing.T) {
+	l := New().WithConfig(&Config{Level: "debug", Format: FormatJSON}).(*Logger)
+	l.Errorw("request failed", "error", &ConfigError{Err: errors.New("no such file"), Reason: "missing"}, "attempt", 3)
+
+	messages := l.GetMessages()
+	require.Len(t, messages, 1)
+
+	var decoded struct {
+		Error struct {
+			Fields  map[string]interface{} `json:"fields"`
+			Message string                 `json:"message"`
+			Type    string                 `json:"type"`
+			Causes  []struct {
+				Message string `json:"message"`
+			} `json:"causes"`
+		} `json:"error"`
+		Attempt int `json:"attempt"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(messages[0]), &decoded))
+	assert.Equal(t, 3, decoded.Attempt)
+	assert.Equal(t, "*logger.ConfigError", decoded.Error.Type)
+	assert.Equal(t, "missing", decoded.Error.Fields["reason"])
+	require.Len(t, decoded.Error.Causes, 1)
+	assert.Equal(t, "no such file", decoded.Error.Causes[0].Message)
+}
+
+func TestErrorwOddArguments(t *testing.T) {
+	l := New()
+	l.Errorw("odd", "key", "value", "dangling")
+
+	messages := l.GetMessages()
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "[!BADKEY=dangling key=value]")
+}
+
+// panicError ошибка, метод Error которой завершается паникой
+type panicError struct{}
+
+func (panicError) Error() string { panic("broken") }
+
+func TestErrorFieldTypedNil(t *testing.T) {
+	var cfgErr *ConfigError
+
+	l := New().WithStacktrace(InfoLevel).WithFields(Fields{ErrorKey: cfgErr})
+	require.NotPanics(t, func() { l.Info("typed nil") })
+	messages := l.GetMessages()
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "[error=<nil> error.type=*logger.ConfigError]")
+
+	line := JSONEncoder{}.Encode(&Record{Level: InfoLevel, Fields: map[string]interface{}{"err": cfgErr}})
+	assert.Contains(t, line, `"err":{"message":"<nil>","type":"*logger.ConfigError"}`)
+
+	info := describeError(panicError{}, 0)
+	assert.Contains(t, info.Message, "PANIC")
+}
+
+func TestErrorwThroughInterface(t *testing.T) {
+	var l ILogger = New()
+	l.Errorw("request failed", "attempt", 3)
+
+	messages := l.GetMessages()
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "request failed [attempt=3]")
+
+	mock := NewMockLogger()
+	mock.Errorw("mock failed", "attempt", 3)
+	assert.Equal(t, []string{"[ERROR] mock failed [attempt=3]"}, mock.Messages)
+}
@@ -16,6 +16,11 @@ func (e *ConfigError) Unwrap() error {
 	return e.Err
 }
 
+// LogFields возвращает структурированные поля ошибки конфигурации
+func (e *ConfigError) LogFields() map[string]interface{} {
+	return map[string]interface{}{"reason": e.Reason}
+}
+
 func (e *WriteError) Error() string {
 	if e.Cause != nil {
 		return fmt.Sprintf("ошибка записи лога: %s (причина: %v)", e.Message, e.Cause)
@@ -27,6 +32,11 @@ func (e *WriteError) Unwrap() error {
 	return e.Cause
 }
 
+// LogFields возвращает структурированные поля ошибки записи
+func (e *WriteError) LogFields() map[string]interface{} {
+	return map[string]interface{}{"message": e.Message}
+}
+
 // LevelError представляет ошибку неверного уровня логирования
 type LevelError struct {
 	Level string
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ControlPolicy определяет обработку управляющих символов в текстовом формате.
+// Непрошедшие обработку переводы строк и ANSI-последовательности позволяют
+// подделывать записи лога через пользовательские данные
+type ControlPolicy int
+
+// Политики обработки управляющих символов
+const (
+	// ControlEscape заменяет управляющие символы escape-последовательностями (\n, \r, \x1b),
+	// а обратную косую черту удваивает, чтобы исходный текст "\n" отличался от перевода строки
+	ControlEscape ControlPolicy = iota
+	// ControlStrip удаляет управляющие символы
+	ControlStrip
+	// ControlRaw выводит сообщения без изменений
+	ControlRaw
+)
+
+// ParseControlPolicy преобразует название политики ("escape", "strip", "raw") в ControlPolicy
+func ParseControlPolicy(policy string) (ControlPolicy, error) {
+	switch strings.ToLower(policy) {
+	case "", "escape":
+		return ControlEscape, nil
+	case "strip":
+		return ControlStrip, nil
+	case "raw":
+		return ControlRaw, nil
+	default:
+		return ControlEscape, fmt.Errorf("неизвестная политика управляющих символов: %s", policy)
+	}
+}
+
+// sanitize обрабатывает управляющие символы строки согласно политике.
+// Табуляция считается безопасной и сохраняется
+func (p ControlPolicy) sanitize(s string) string {
+	if p == ControlRaw || !hasControl(s) && (p == ControlStrip || !strings.Contains(s, `\`)) {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for _, r := range s {
+		if r == '\\' && p == ControlEscape {
+			b.WriteString(`\\`)
+			continue
+		}
+		if !isControl(r) {
+			b.WriteRune(r)
+			continue
+		}
+		if p == ControlStrip {
+			continue
+		}
+		switch r {
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x100 {
+				fmt.Fprintf(&b, `\x%02x`, r)
+			} else {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// hasControl быстро проверяет, есть ли в строке символы, требующие обработки
+func hasControl(s string) bool {
+	for _, r := range s {
+		if isControl(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// isControl сообщает, является ли символ управляющим: C0 (кроме табуляции), DEL, C1,
+// а также разделители строк и абзацев Unicode
+func isControl(r rune) bool {
+	switch {
+	case r == '\t':
+		return false
+	case r < 0x20, r == 0x7f, r >= 0x80 && r <= 0x9f:
+		return true
+	case r == '\u2028', r == '\u2029':
+		return true
+	}
+	return false
+}
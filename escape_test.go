@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPolicySanitize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		escaped  string
+		stripped string
+	}{
+		{
+			name:     "перевод строки",
+			input:    "user\n2024-01-01 00:00:00 [ERROR] fake",
+			escaped:  `user\n2024-01-01 00:00:00 [ERROR] fake`,
+			stripped: "user2024-01-01 00:00:00 [ERROR] fake",
+		},
+		{
+			name:     "возврат каретки",
+			input:    "a\r\nb",
+			escaped:  `a\r\nb`,
+			stripped: "ab",
+		},
+		{
+			name:     "ANSI-последовательность",
+			input:    "\x1b[31mred\x1b[0m",
+			escaped:  `\x1b[31mred\x1b[0m`,
+			stripped: "[31mred[0m",
+		},
+		{
+			name:     "разделитель строк Unicode и C1",
+			input:    "a\u2028b\u0085c",
+			escaped:  `a\u2028b\x85c`,
+			stripped: "abc",
+		},
+		{
+			name:     "обратная косая черта",
+			input:    `literal \n` + "\nreal",
+			escaped:  `literal \\n\nreal`,
+			stripped: `literal \nreal`,
+		},
+		{
+			name:     "табуляция и кириллица сохраняются",
+			input:    "ключ\tзначение",
+			escaped:  "ключ\tзначение",
+			stripped: "ключ\tзначение",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.escaped, ControlEscape.sanitize(tt.input))
+			assert.Equal(t, tt.stripped, ControlStrip.sanitize(tt.input))
+			assert.Equal(t, tt.input, ControlRaw.sanitize(tt.input))
+		})
+	}
+}
+
+func TestLogInjection(t *testing.T) {
+	l := New()
+	child := l.WithPrefix("API\n").WithFields(map[string]interface{}{"user": "bob\n[ERROR] forged"})
+	child.Infof("login %s", "ok\n2024-01-01 00:00:00 [ERROR] fake")
+
+	messages := child.GetMessages()
+	require.Len(t, messages, 1)
+	assert.NotContains(t, messages[0], "\n")
+	assert.Contains(t, messages[0], `[API\n] login ok\n2024-01-01 00:00:00 [ERROR] fake [user=bob\n[ERROR] forged]`)
+}
+
+func TestStackLinesIndented(t *testing.T) {
+	record := &Record{
+		Time:    time.Date(2024, 1, 30, 10, 20, 30, 0, time.UTC),
+		Level:   ErrorLevel,
+		Message: "failed",
+		Stack:   "main.main\n\t/src/main.go:10\n2024-01-30 10:20:30 [INFO] forged",
+	}
+
+	lines := strings.Split(TextEncoder{}.Encode(record), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "2024-01-30 10:20:30 [ERROR] failed", lines[0])
+	for _, line := range lines[1:] {
+		assert.True(t, strings.HasPrefix(line, "\t"), "строка трассировки должна начинаться с табуляции: %q", line)
+	}
+
+	raw := TextEncoder{Control: ControlRaw}.Encode(record)
+	assert.Equal(t, "2024-01-30 10:20:30 [ERROR] failed\n"+record.Stack, raw)
+}
+
+func TestConfigControlChars(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ControlChars = "strip"
+	require.NoError(t, cfg.Validate())
+
+	l := New().WithConfig(cfg)
+	l.Info("a\nb")
+	messages := l.GetMessages()
+	require.Len(t, messages, 1)
+	assert.True(t, strings.HasSuffix(messages[0], "[INFO] ab"))
+
+	cfg.ControlChars = "keep"
+	require.Error(t, cfg.Validate())
+}
@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Filter решает, пропускать ли запись. Фильтры вычисляются до кодирования,
+// поэтому отброшенные записи не тратят время на форматирование
+type Filter interface {
+	Allow(r *Record) bool
+}
+
+// FilterFunc позволяет использовать функцию как Filter
+type FilterFunc func(r *Record) bool
+
+// Allow реализует интерфейс Filter
+func (f FilterFunc) Allow(r *Record) bool {
+	return f(r)
+}
+
+// LevelFilter пропускает записи уровня min и выше
+func LevelFilter(min Level) Filter {
+	return FilterFunc(func(r *Record) bool {
+		return r.Level >= min
+	})
+}
+
+// PrefixFilter пропускает записи с одним из указанных префиксов или их потомков:
+// фильтр "PAYMENTS" пропускает также "PAYMENTS.REFUNDS"
+func PrefixFilter(prefixes ...string) Filter {
+	return FilterFunc(func(r *Record) bool {
+		for _, p := range prefixes {
+			if r.Prefix == p || strings.HasPrefix(r.Prefix, p+".") {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// FieldFilter пропускает записи, у которых поле key имеет строковое представление value
+func FieldFilter(key, value string) Filter {
+	return FilterFunc(func(r *Record) bool {
+		v, ok := r.Fields[key]
+		return ok && fmt.Sprint(v) == value
+	})
+}
+
+// MessageFilter пропускает записи, сообщение которых соответствует регулярному выражению
+func MessageFilter(re *regexp.Regexp) Filter {
+	return FilterFunc(func(r *Record) bool {
+		return re.MatchString(r.Message)
+	})
+}
+
+// Not инвертирует фильтр
+func Not(f Filter) Filter {
+	return FilterFunc(func(r *Record) bool {
+		return !f.Allow(r)
+	})
+}
+
+// All пропускает запись, если ее пропускают все фильтры
+func All(filters ...Filter) Filter {
+	return FilterFunc(func(r *Record) bool {
+		for _, f := range filters {
+			if !f.Allow(r) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Any пропускает запись, если ее пропускает хотя бы один фильтр
+func Any(filters ...Filter) Filter {
+	return FilterFunc(func(r *Record) bool {
+		for _, f := range filters {
+			if f.Allow(r) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Действия правил фильтрации
+const (
+	FilterInclude = "include"
+	FilterExclude = "exclude"
+)
+
+// FilterRule декларативное правило фильтрации. Правило срабатывает, если запись
+// удовлетворяет всем заданным условиям; пустые условия не проверяются
+type FilterRule struct {
+	// Action действие правила: "include" или "exclude"
+	Action string
+	// MinLevel минимальный уровень записи
+	MinLevel string
+	// Prefix префикс записи (включая дочерние префиксы)
+	Prefix string
+	// Field имя поля, значение которого должно быть равно Value
+	Field string
+	// Value ожидаемое значение поля Field
+	Value string
+	// Message регулярное выражение для текста сообщения
+	Message string
+}
+
+// NewFilter строит фильтр по набору правил. Запись отбрасывается, если она
+// удовлетворяет хотя бы одному правилу exclude, а при наличии правил include
+// должна удовлетворять хотя бы одному из них
+func NewFilter(rules []FilterRule) (Filter, error) {
+	var includes, excludes []Filter
+	for _, rule := range rules {
+		f, err := rule.build()
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(rule.Action) {
+		case FilterInclude:
+			includes = append(includes, f)
+		case FilterExclude:
+			excludes = append(excludes, f)
+		default:
+			return nil, &ConfigError{
+				Err:    fmt.Errorf("неизвестное действие фильтра: %s", rule.Action),
+				Reason: "некорректное правило фильтрации",
+			}
+		}
+	}
+
+	chain := []Filter{Not(Any(excludes...))}
+	if len(includes) > 0 {
+		chain = append(chain, Any(includes...))
+	}
+	return All(chain...), nil
+}
+
+// build строит фильтр, проверяющий все условия правила
+func (rule FilterRule) build() (Filter, error) {
+	var conditions []Filter
+	if rule.MinLevel != "" {
+		level, err := ParseLevel(rule.MinLevel)
+		if err != nil {
+			return nil, &ConfigError{Err: err, Reason: "некорректный уровень в правиле фильтрации"}
+		}
+		conditions = append(conditions, LevelFilter(level))
+	}
+	if rule.Prefix != "" {
+		conditions = append(conditions, PrefixFilter(rule.Prefix))
+	}
+	if rule.Field != "" {
+		conditions = append(conditions, FieldFilter(rule.Field, rule.Value))
+	}
+	if rule.Message != "" {
+		re, err := regexp.Compile(rule.Message)
+		if err != nil {
+			return nil, &ConfigError{Err: err, Reason: "некорректное регулярное выражение в правиле фильтрации"}
+		}
+		conditions = append(conditions, MessageFilter(re))
+	}
+	return All(conditions...), nil
+}
+
+// filteredSink передает в назначение только записи, пропущенные фильтром
+type filteredSink struct {
+	Sink
+	filter Filter
+}
+
+// NewFilteredSink оборачивает назначение фильтром
+func NewFilteredSink(s Sink, f Filter) Sink {
+	return &filteredSink{Sink: s, filter: f}
+}
+
+// Write реализует интерфейс Sink
+func (s *filteredSink) Write(r *Record) error {
+	if !s.filter.Allow(r) {
+		return nil
+	}
+	return s.Sink.Write(r)
+}
+
+// allowed проверяет запись всеми фильтрами логгера
+func (l *Logger) allowed(r *Record) bool {
+	for _, f := range l.filters {
+		if !f.Allow(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// WithFilter создает новый логгер, записывающий только записи, пропущенные фильтром.
+// Фильтры накапливаются и наследуются дочерними логгерами
+func (l *Logger) WithFilter(f Filter) *Logger {
+	newLogger := l.clone()
+	newLogger.filters = append(append([]Filter{}, l.filters...), f)
+	return newLogger
+}
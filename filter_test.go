@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterCombinators(t *testing.T) {
+	r := &Record{
+		Level:   WarningLevel,
+		Prefix:  "PAYMENTS.REFUNDS",
+		Message: "refund issued",
+		Fields:  map[string]interface{}{"status": 200},
+	}
+
+	assert.True(t, LevelFilter(InfoLevel).Allow(r))
+	assert.False(t, LevelFilter(ErrorLevel).Allow(r))
+	assert.True(t, PrefixFilter("PAYMENTS").Allow(r))
+	assert.False(t, PrefixFilter("PAY").Allow(r))
+	assert.True(t, FieldFilter("status", "200").Allow(r))
+	assert.False(t, FieldFilter("missing", "").Allow(r))
+	assert.True(t, MessageFilter(regexp.MustCompile(`^refund`)).Allow(r))
+
+	assert.False(t, Not(LevelFilter(InfoLevel)).Allow(r))
+	assert.True(t, All().Allow(r))
+	assert.False(t, Any().Allow(r))
+	assert.True(t, Any(LevelFilter(ErrorLevel), PrefixFilter("PAYMENTS")).Allow(r))
+	assert.False(t, All(LevelFilter(ErrorLevel), PrefixFilter("PAYMENTS")).Allow(r))
+}
+
+func TestNewFilterRules(t *testing.T) {
+	f, err := NewFilter([]FilterRule{
+		{Action: FilterInclude, Prefix: "API"},
+		{Action: FilterInclude, MinLevel: "error"},
+		{Action: FilterExclude, Message: `^GET /health`},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, f.Allow(&Record{Level: InfoLevel, Prefix: "API", Message: "GET /users"}))
+	assert.False(t, f.Allow(&Record{Level: InfoLevel, Prefix: "API", Message: "GET /healthz"}))
+	assert.True(t, f.Allow(&Record{Level: ErrorLevel, Prefix: "DB", Message: "timeout"}))
+	assert.False(t, f.Allow(&Record{Level: InfoLevel, Prefix: "DB", Message: "connected"}))
+
+	empty, err := NewFilter(nil)
+	require.NoError(t, err)
+	assert.True(t, empty.Allow(&Record{}))
+
+	_, err = NewFilter([]FilterRule{{Action: "drop"}})
+	var cfgErr *ConfigError
+	assert.ErrorAs(t, err, &cfgErr)
+	_, err = NewFilter([]FilterRule{{Action: FilterExclude, Message: "("}})
+	assert.ErrorAs(t, err, &cfgErr)
+	_, err = NewFilter([]FilterRule{{Action: FilterExclude, MinLevel: "loud"}})
+	assert.ErrorAs(t, err, &cfgErr)
+}
+
+func TestLoggerFilterSkipsEncoding(t *testing.T) {
+	encoded := 0
+	l := New().WithFilter(Not(FieldFilter("path", "/health")))
+	l.encoder = countingEncoder{count: &encoded}
+
+	child := l.WithFields(map[string]interface{}{"path": "/health"})
+	child.Info("probe")
+	assert.Empty(t, child.GetMessages())
+	assert.Equal(t, 0, encoded)
+
+	other := l.WithFields(map[string]interface{}{"path": "/orders"})
+	other.Info("order")
+	assert.Len(t, other.GetMessages(), 1)
+	assert.Equal(t, 1, encoded)
+}
+
+func TestConfigFilters(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Filters = []FilterRule{{Action: FilterExclude, Message: "health"}}
+	require.NoError(t, cfg.Validate())
+
+	l := New().WithConfig(cfg)
+	l.Info("health check")
+	l.Info("started")
+	msgs := l.GetMessages()
+	require.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "started")
+
+	cfg.Filters = []FilterRule{{Action: "keep"}}
+	assert.Error(t, cfg.Validate())
+}
+
+// countingEncoder считает вызовы кодирования
+type countingEncoder struct {
+	count *int
+}
+
+func (e countingEncoder) Encode(r *Record) string {
+	*e.count++
+	return r.Message
+}
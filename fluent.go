@@ -0,0 +1,378 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultFluentTag тег записей без префикса
+const DefaultFluentTag = "logger"
+
+// FluentConfig параметры назначения Fluentd или Fluent Bit
+type FluentConfig struct {
+	BatchConfig
+	RetryConfig
+	// TLS включает TLS поверх TCP
+	TLS *tls.Config
+	// Network тип соединения: "tcp" (по умолчанию) или "unix"
+	Network string
+	// Address адрес входа forward, например localhost:24224
+	Address string
+	// Tag тег записей без префикса, по умолчанию DefaultFluentTag
+	Tag string
+	// TagPrefix добавляется к префиксу записи через точку: "app" и "API" дают тег "app.API"
+	TagPrefix string
+	// RequireAck включает подтверждение приема каждого пакета сервером
+	RequireAck bool
+	// Timeout таймаут подключения, записи и ожидания подтверждения,
+	// по умолчанию DefaultNetworkTimeout
+	Timeout time.Duration
+}
+
+// FluentStats счетчики назначения Fluent
+type FluentStats struct {
+	SentRecords    uint64
+	DroppedRecords uint64
+	Batches        uint64
+	Reconnects     uint64
+}
+
+// FluentSink передает записи по протоколу Fluent Forward в режиме PackedForward:
+// записи пакета с одинаковым тегом отправляются одним сообщением. Тегом служит
+// префикс записи. При ошибке соединение устанавливается заново и пакет
+// отправляется повторно; пока коллектор недоступен, пакеты копятся в очереди
+type FluentSink struct {
+	stats   FluentStats // первое поле для выравнивания атомарных счетчиков на 32-битных платформах
+	cfg     FluentConfig
+	batcher *batcher
+	conn    net.Conn
+	reader  *bufio.Reader
+	// sleep выдерживает паузу между попытками; false означает, что повторы прерваны
+	sleep   func(time.Duration) bool
+	abort   chan struct{}
+	stopped sync.Once
+}
+
+// fluentMessage сообщение PackedForward с записями одного тега
+type fluentMessage struct {
+	data  []byte
+	chunk string
+	count int
+}
+
+// NewFluentSink создает назначение Fluent. Подключение выполняется при отправке
+// первого пакета, поэтому недоступность коллектора при запуске не является ошибкой
+func NewFluentSink(cfg FluentConfig) (*FluentSink, error) {
+	if cfg.Network == "" {
+		cfg.Network = "tcp"
+	}
+	if cfg.Network != "tcp" && cfg.Network != "tcp4" && cfg.Network != "tcp6" && cfg.Network != "unix" {
+		return nil, &ConfigError{Err: fmt.Errorf("неподдерживаемый тип соединения: %s", cfg.Network), Reason: "некорректное назначение Fluent"}
+	}
+	if cfg.Address == "" {
+		return nil, &ConfigError{Err: fmt.Errorf("не указан адрес"), Reason: "некорректное назначение Fluent"}
+	}
+	if cfg.Tag == "" {
+		cfg.Tag = DefaultFluentTag
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultNetworkTimeout
+	}
+	cfg.RetryConfig = cfg.RetryConfig.withDefaults()
+
+	s := &FluentSink{cfg: cfg, abort: make(chan struct{})}
+	s.sleep = s.pause
+	s.batcher = newBatcher(cfg.BatchConfig, s.flush)
+	return s, nil
+}
+
+// Write реализует интерфейс Sink. Запись кодируется в элемент [время, запись]
+func (s *FluentSink) Write(r *Record) error {
+	if !s.batcher.add(batchEntry{record: r, data: encodeFluentEntry(r)}) {
+		return &WriteError{Message: "назначение fluent закрыто"}
+	}
+	return nil
+}
+
+// tag возвращает тег записи
+func (s *FluentSink) tag(r *Record) string {
+	if r.Prefix == "" {
+		return s.cfg.Tag
+	}
+	if s.cfg.TagPrefix != "" {
+		return s.cfg.TagPrefix + "." + r.Prefix
+	}
+	return r.Prefix
+}
+
+// messages группирует записи пакета по тегам в порядке появления
+func (s *FluentSink) messages(batch []batchEntry) ([]fluentMessage, error) {
+	var tags []string
+	entries := make(map[string][]byte)
+	counts := make(map[string]int)
+	for _, e := range batch {
+		tag := s.tag(e.record)
+		if _, ok := entries[tag]; !ok {
+			tags = append(tags, tag)
+		}
+		entries[tag] = append(entries[tag], e.data...)
+		counts[tag]++
+	}
+
+	messages := make([]fluentMessage, 0, len(tags))
+	for _, tag := range tags {
+		var id [16]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			return nil, err
+		}
+		msg := fluentMessage{chunk: base64.StdEncoding.EncodeToString(id[:]), count: counts[tag]}
+
+		// [tag, entries, {"size": n, "chunk": id}]
+		var m msgpackBuffer
+		m.array(3)
+		m.string(tag)
+		m.binary(entries[tag])
+		if s.cfg.RequireAck {
+			m.mapHeader(2)
+			m.string("chunk")
+			m.string(msg.chunk)
+		} else {
+			m.mapHeader(1)
+		}
+		m.string("size")
+		m.uint(uint64(msg.count))
+		msg.data = m.b
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// flush отправляет пакет, повторяя попытки с экспоненциальной паузой.
+// Сообщения, принятые до ошибки, повторно не отправляются. После прерывания
+// повторов при закрытии оставшиеся сообщения отбрасываются без новых попыток
+func (s *FluentSink) flush(batch []batchEntry) {
+	atomic.AddUint64(&s.stats.Batches, 1)
+
+	messages, err := s.messages(batch)
+	if err != nil {
+		atomic.AddUint64(&s.stats.DroppedRecords, uint64(len(batch)))
+		reportSinkError("fluent", err)
+		return
+	}
+
+	backoff := s.cfg.MinBackoff
+	for attempt := 0; len(messages) > 0; {
+		if s.aborted() {
+			s.drop(messages, errRetriesAborted)
+			return
+		}
+		err := s.send(messages[0])
+		if err == nil {
+			atomic.AddUint64(&s.stats.SentRecords, uint64(messages[0].count))
+			messages = messages[1:]
+			continue
+		}
+
+		s.disconnect()
+		if attempt >= s.cfg.MaxRetries || !s.sleep(backoff) {
+			s.drop(messages, err)
+			return
+		}
+		attempt++
+		if backoff *= 2; backoff > s.cfg.MaxBackoff {
+			backoff = s.cfg.MaxBackoff
+		}
+	}
+}
+
+// drop учитывает неотправленные сообщения как отброшенные
+func (s *FluentSink) drop(messages []fluentMessage, err error) {
+	for _, msg := range messages {
+		atomic.AddUint64(&s.stats.DroppedRecords, uint64(msg.count))
+	}
+	reportSinkError("fluent", err)
+}
+
+// pause выдерживает паузу перед повтором; возвращает false, если повторы прерваны при закрытии
+func (s *FluentSink) pause(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-s.abort:
+		return false
+	}
+}
+
+// aborted сообщает, прерваны ли повторы при закрытии
+func (s *FluentSink) aborted() bool {
+	select {
+	case <-s.abort:
+		return true
+	default:
+		return false
+	}
+}
+
+// send отправляет сообщение и при необходимости дожидается подтверждения
+func (s *FluentSink) send(msg fluentMessage) error {
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.conn.SetDeadline(time.Now().Add(s.cfg.Timeout)); err != nil {
+		return err
+	}
+	if _, err := s.conn.Write(msg.data); err != nil {
+		return err
+	}
+	if !s.cfg.RequireAck {
+		return nil
+	}
+
+	ack, err := readFluentAck(s.reader)
+	if err != nil {
+		return fmt.Errorf("не получено подтверждение: %w", err)
+	}
+	if ack != msg.chunk {
+		return fmt.Errorf("подтверждение %q не соответствует пакету %q", ack, msg.chunk)
+	}
+	return nil
+}
+
+// connect подключается к коллектору
+func (s *FluentSink) connect() error {
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+	var conn net.Conn
+	var err error
+	if s.cfg.TLS != nil {
+		conn, err = tls.DialWithDialer(dialer, s.cfg.Network, s.cfg.Address, s.cfg.TLS)
+	} else {
+		conn, err = dialer.Dial(s.cfg.Network, s.cfg.Address)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	s.reader = bufio.NewReader(conn)
+	return nil
+}
+
+// disconnect закрывает соединение после ошибки
+func (s *FluentSink) disconnect() {
+	if s.conn == nil {
+		return
+	}
+	_ = s.conn.Close()
+	s.conn = nil
+	s.reader = nil
+	atomic.AddUint64(&s.stats.Reconnects, 1)
+}
+
+// Stats возвращает счетчики назначения
+func (s *FluentSink) Stats() FluentStats {
+	return FluentStats{
+		SentRecords:    atomic.LoadUint64(&s.stats.SentRecords),
+		DroppedRecords: atomic.LoadUint64(&s.stats.DroppedRecords) + s.batcher.Dropped(),
+		Batches:        atomic.LoadUint64(&s.stats.Batches),
+		Reconnects:     atomic.LoadUint64(&s.stats.Reconnects),
+	}
+}
+
+// Close реализует интерфейс Sink. Отправляет накопленные записи и закрывает соединение.
+// Если отправка не завершилась за Timeout, повторы прерываются, а неотправленные
+// записи отбрасываются; в этом случае возвращается ошибка
+func (s *FluentSink) Close() error {
+	closed := make(chan struct{})
+	go func() {
+		s.batcher.close()
+		close(closed)
+	}()
+
+	var err error
+	timer := time.NewTimer(s.cfg.Timeout)
+	defer timer.Stop()
+	select {
+	case <-closed:
+	case <-timer.C:
+		before := s.Stats().DroppedRecords
+		s.stopped.Do(func() { close(s.abort) })
+		<-closed
+		if dropped := s.Stats().DroppedRecords - before; dropped > 0 {
+			err = &WriteError{Message: fmt.Sprintf("назначение fluent: отброшено записей: %d", dropped)}
+		}
+	}
+
+	if s.conn != nil {
+		if closeErr := s.conn.Close(); err == nil {
+			err = closeErr
+		}
+		s.conn = nil
+	}
+	return err
+}
+
+// encodeFluentEntry кодирует запись в элемент [EventTime, {ключ: значение}]
+// с теми же ключами, что и JSONEncoder, кроме времени
+func encodeFluentEntry(r *Record) []byte {
+	record := make(map[string]interface{}, len(r.Fields)+5)
+	for k, v := range r.Fields {
+		record[k] = v
+	}
+	record[LevelKey] = r.Level.String()
+	if r.Prefix != "" {
+		record[PrefixKey] = r.Prefix
+	}
+	record[MessageKey] = r.Message
+	if r.Caller != nil {
+		record[CallerKey] = r.Caller.String()
+		if r.Caller.Function != "" {
+			record[FunctionKey] = r.Caller.Function
+		}
+	}
+	if r.Stack != "" {
+		record[StacktraceKey] = r.Stack
+	}
+
+	var m msgpackBuffer
+	m.array(2)
+	m.eventTime(r.Time)
+	m.value(record)
+	return m.b
+}
+
+// readFluentAck читает ответ сервера {"ack": "<chunk>"}
+func readFluentAck(r *bufio.Reader) (string, error) {
+	n, err := readMsgpackMapHeader(r)
+	if err != nil {
+		return "", err
+	}
+	ack := ""
+	for i := 0; i < n; i++ {
+		key, err := readMsgpackString(r)
+		if err != nil {
+			return "", err
+		}
+		value, err := readMsgpackString(r)
+		if err != nil {
+			return "", err
+		}
+		if key == "ack" {
+			ack = value
+		}
+	}
+	if ack == "" {
+		return "", fmt.Errorf("ответ не содержит ack")
+	}
+	return ack, nil
+}
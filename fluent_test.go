@@ -0,0 +1,352 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// msgpackDecode разбирает одно значение MessagePack. EventTime возвращается как time.Time
+func msgpackDecode(r *bufio.Reader) (interface{}, error) {
+	format, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	read := func(n int) ([]byte, error) {
+		buf := make([]byte, n)
+		_, err := io.ReadFull(r, buf)
+		return buf, err
+	}
+	// bits читает беззнаковое число из n байт; через int значения обрезались бы на 32-битных платформах
+	bits := func(n int) (uint64, error) {
+		buf, err := read(n)
+		if err != nil {
+			return 0, err
+		}
+		var v uint64
+		for _, c := range buf {
+			v = v<<8 | uint64(c)
+		}
+		return v, nil
+	}
+	length := func(n int) (int, error) {
+		v, err := bits(n)
+		return int(v), err
+	}
+	collection := func(n int, isMap bool) (interface{}, error) {
+		if isMap {
+			m := make(map[string]interface{}, n)
+			for i := 0; i < n; i++ {
+				k, err := msgpackDecode(r)
+				if err != nil {
+					return nil, err
+				}
+				v, err := msgpackDecode(r)
+				if err != nil {
+					return nil, err
+				}
+				m[fmt.Sprint(k)] = v
+			}
+			return m, nil
+		}
+		a := make([]interface{}, n)
+		for i := range a {
+			if a[i], err = msgpackDecode(r); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	}
+
+	switch {
+	case format < 0x80:
+		return int64(format), nil
+	case format >= 0xe0:
+		return int64(int8(format)), nil
+	case format&0xf0 == 0x80:
+		return collection(int(format&0x0f), true)
+	case format&0xf0 == 0x90:
+		return collection(int(format&0x0f), false)
+	case format&0xe0 == 0xa0:
+		buf, err := read(int(format & 0x1f))
+		return string(buf), err
+	}
+
+	switch format {
+	case 0xc0:
+		return nil, nil
+	case 0xc2, 0xc3:
+		return format == 0xc3, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := length(1 << (format - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return read(n)
+	case 0xcb:
+		buf, err := read(8)
+		return math.Float64frombits(binary.BigEndian.Uint64(buf)), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := bits(1 << (format - 0xcc))
+		return int64(n), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (format - 0xd0)
+		n, err := bits(size)
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, err
+	case 0xd7:
+		buf, err := read(9)
+		if err != nil || buf[0] != 0 {
+			return nil, errors.New("неизвестное расширение")
+		}
+		return time.Unix(int64(binary.BigEndian.Uint32(buf[1:5])), int64(binary.BigEndian.Uint32(buf[5:]))), nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := length(1 << (format - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		buf, err := read(n)
+		return string(buf), err
+	case 0xdc, 0xdd:
+		n, err := length(2 << (format - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return collection(n, false)
+	case 0xde, 0xdf:
+		n, err := length(2 << (format - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return collection(n, true)
+	}
+	return nil, fmt.Errorf("неизвестный формат 0x%02x", format)
+}
+
+// fluentEvent запись, принятая фиктивным сервером forward
+type fluentEvent struct {
+	record map[string]interface{}
+	time   time.Time
+	tag    string
+}
+
+// fluentServer фиктивный сервер forward. Первые drop соединений закрываются
+// после чтения сообщения без подтверждения
+func fluentServer(t *testing.T, drop int) (string, <-chan fluentEvent) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	events := make(chan fluentEvent, 64)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			dropConn := drop > 0
+			drop--
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					msg, err := msgpackDecode(r)
+					if err != nil || dropConn {
+						return
+					}
+					parts := msg.([]interface{})
+					entries := bufio.NewReader(strings.NewReader(string(parts[1].([]byte))))
+					for {
+						entry, err := msgpackDecode(entries)
+						if err != nil {
+							break
+						}
+						pair := entry.([]interface{})
+						events <- fluentEvent{tag: parts[0].(string), time: pair[0].(time.Time), record: pair[1].(map[string]interface{})}
+					}
+					option := parts[2].(map[string]interface{})
+					if chunk, ok := option["chunk"]; ok {
+						var m msgpackBuffer
+						m.mapHeader(1)
+						m.string("ack")
+						m.string(chunk.(string))
+						if _, err := conn.Write(m.b); err != nil {
+							return
+						}
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), events
+}
+
+func receiveFluent(t *testing.T, events <-chan fluentEvent) fluentEvent {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("запись не получена")
+		return fluentEvent{}
+	}
+}
+
+func TestMsgpackValues(t *testing.T) {
+	values := []interface{}{
+		nil, true, false, 0, 127, 128, 65536, -1, -33, -200, -40000, int64(-3000000000), uint64(1 << 40),
+		1.5, "", strings.Repeat("s", 40), strings.Repeat("l", 70000),
+		[]interface{}{int64(1), "a"}, map[string]interface{}{"k": "v"},
+	}
+	for _, v := range values {
+		var m msgpackBuffer
+		m.value(v)
+		decoded, err := msgpackDecode(bufio.NewReader(strings.NewReader(string(m.b))))
+		require.NoError(t, err)
+		switch v := v.(type) {
+		case int:
+			assert.Equal(t, int64(v), decoded)
+		case uint64:
+			assert.Equal(t, int64(v), decoded)
+		default:
+			assert.Equal(t, v, decoded)
+		}
+	}
+
+	// Значения вне диапазона int32 кодируются как int64
+	var wide msgpackBuffer
+	wide.value(int64(-3000000000))
+	assert.Equal(t, []byte{0xd3, 0xff, 0xff, 0xff, 0xff, 0x4d, 0x2f, 0xa2, 0x00}, wide.b)
+
+	// Структуры и ошибки приводятся к тому же виду, что и в JSON
+	var m msgpackBuffer
+	m.value(struct {
+		Name string `json:"name"`
+	}{Name: "x"})
+	m.value(errors.New("boom"))
+	m.value(time.Second)
+	r := bufio.NewReader(strings.NewReader(string(m.b)))
+	decoded, err := msgpackDecode(r)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "x"}, decoded)
+	decoded, err = msgpackDecode(r)
+	require.NoError(t, err)
+	assert.Equal(t, "boom", decoded.(map[string]interface{})["message"])
+	decoded, err = msgpackDecode(r)
+	require.NoError(t, err)
+	assert.Equal(t, "1s", decoded)
+}
+
+func TestFluentSinkPackedForwardWithAck(t *testing.T) {
+	addr, events := fluentServer(t, 0)
+
+	sink, err := NewFluentSink(FluentConfig{Address: addr, TagPrefix: "app", RequireAck: true})
+	require.NoError(t, err)
+
+	l := New().WithSink(sink)
+	l.WithPrefix("API").WithFields(Fields{"user": "bob", "attempt": 3}).Info("first")
+	l.Warning("second")
+	l.WithPrefix("API").Error("third")
+	require.NoError(t, l.Close())
+
+	// Записи одного тега приходят одним сообщением в порядке записи
+	byTag := make(map[string][]fluentEvent)
+	for i := 0; i < 3; i++ {
+		e := receiveFluent(t, events)
+		byTag[e.tag] = append(byTag[e.tag], e)
+	}
+	require.Len(t, byTag["app.API"], 2)
+	require.Len(t, byTag[DefaultFluentTag], 1)
+
+	first := byTag["app.API"][0]
+	assert.Equal(t, "first", first.record[MessageKey])
+	assert.Equal(t, "INFO", first.record[LevelKey])
+	assert.Equal(t, "API", first.record[PrefixKey])
+	assert.Equal(t, "bob", first.record["user"])
+	assert.Equal(t, int64(3), first.record["attempt"])
+	assert.WithinDuration(t, time.Now(), first.time, time.Minute)
+	assert.Equal(t, "third", byTag["app.API"][1].record[MessageKey])
+	assert.Equal(t, "second", byTag[DefaultFluentTag][0].record[MessageKey])
+
+	stats := sink.Stats()
+	assert.Equal(t, uint64(3), stats.SentRecords)
+	assert.Zero(t, stats.DroppedRecords)
+	assert.Error(t, sink.Write(&Record{Message: "late"}))
+}
+
+func TestFluentSinkReconnects(t *testing.T) {
+	// Первое соединение обрывается без подтверждения, пакет отправляется повторно
+	addr, events := fluentServer(t, 1)
+
+	sink, err := NewFluentSink(FluentConfig{
+		Address:     addr,
+		RequireAck:  true,
+		RetryConfig: RetryConfig{MinBackoff: time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: InfoLevel, Message: "retried"}))
+	require.NoError(t, sink.Close())
+
+	assert.Equal(t, "retried", receiveFluent(t, events).record[MessageKey])
+	stats := sink.Stats()
+	assert.Equal(t, uint64(1), stats.SentRecords)
+	assert.Equal(t, uint64(1), stats.Reconnects)
+	assert.Zero(t, stats.DroppedRecords)
+}
+
+func TestFluentSinkDropsAfterRetries(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	sink, err := NewFluentSink(FluentConfig{
+		Address:     addr,
+		RetryConfig: RetryConfig{MaxRetries: 2, MinBackoff: time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: InfoLevel, Message: "lost"}))
+	require.NoError(t, sink.Close())
+	assert.Equal(t, uint64(1), sink.Stats().DroppedRecords)
+
+	_, err = NewFluentSink(FluentConfig{Network: "udp", Address: addr})
+	var cfgErr *ConfigError
+	assert.ErrorAs(t, err, &cfgErr)
+}
+
+func TestFluentSinkCloseAbortsRetries(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	sink, err := NewFluentSink(FluentConfig{
+		Address:     addr,
+		Timeout:     100 * time.Millisecond,
+		BatchConfig: BatchConfig{MaxRecords: 10},
+		RetryConfig: RetryConfig{MaxRetries: 100, MinBackoff: time.Second, MaxBackoff: time.Minute},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 50; i++ {
+		require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: InfoLevel, Message: "lost"}))
+	}
+	start := time.Now()
+	var writeErr *WriteError
+	assert.ErrorAs(t, sink.Close(), &writeErr)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Equal(t, uint64(50), sink.Stats().DroppedRecords)
+}
@@ -0,0 +1,316 @@
+package logger
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Параметры GELF
+const (
+	// GELFVersion версия формата GELF
+	GELFVersion = "1.1"
+	// DefaultGELFChunkSize размер UDP-фрагмента, безопасный для передачи через интернет
+	DefaultGELFChunkSize = 1420
+	// gelfMaxChunks максимальное количество фрагментов одного сообщения
+	gelfMaxChunks = 128
+	// gelfChunkHeader размер заголовка фрагмента: магические байты, идентификатор, номер и количество
+	gelfChunkHeader = 12
+	// gelfShortMessageLimit максимальная длина short_message
+	gelfShortMessageLimit = 250
+)
+
+// Алгоритмы сжатия GELF поверх UDP
+const (
+	GELFCompressGzip = "gzip"
+	GELFCompressZlib = "zlib"
+	GELFCompressNone = "none"
+)
+
+// GELFEncoder кодирует записи в формат GELF 1.1. Первая строка сообщения
+// передается в short_message, полный текст с трассировкой стека в full_message.
+// Префикс, место вызова и поля записи становятся дополнительными полями с '_'
+type GELFEncoder struct {
+	// Host имя источника, по умолчанию os.Hostname
+	Host string
+}
+
+// Encode реализует интерфейс Encoder
+func (e GELFEncoder) Encode(r *Record) string {
+	host := e.Host
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+
+	short, full := gelfMessages(r)
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	writeJSONField(&b, "version", GELFVersion, true)
+	writeJSONField(&b, "host", host, false)
+	writeJSONField(&b, "short_message", short, false)
+	if full != "" {
+		writeJSONField(&b, "full_message", full, false)
+	}
+	// Время передается в секундах с дробной частью до миллисекунд
+	writeJSONField(&b, "timestamp", math.Round(float64(r.Time.UnixNano())/1e6)/1e3, false)
+	writeJSONField(&b, "level", syslogSeverity(r.Level), false)
+	if r.Prefix != "" {
+		writeJSONField(&b, "_"+PrefixKey, r.Prefix, false)
+	}
+	if r.Caller != nil {
+		writeJSONField(&b, "_"+CallerKey, r.Caller.String(), false)
+		if r.Caller.Function != "" {
+			writeJSONField(&b, "_"+FunctionKey, r.Caller.Function, false)
+		}
+	}
+	for _, k := range sortedKeys(r.Fields) {
+		if err, ok := r.Fields[k].(error); ok && err != nil {
+			for _, p := range describeError(err, 0).flatten(k) {
+				writeJSONField(&b, gelfFieldName(p.Key), p.Value, false)
+			}
+			continue
+		}
+		writeJSONField(&b, gelfFieldName(k), gelfValue(r.Fields[k]), false)
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// gelfMessages делит сообщение на short_message и full_message. Полный текст
+// передается, только если сообщение многострочное, длинное или есть трассировка стека
+func gelfMessages(r *Record) (string, string) {
+	short := r.Message
+	if i := strings.IndexByte(short, '\n'); i >= 0 {
+		short = short[:i]
+	}
+	if len(short) > gelfShortMessageLimit {
+		// обрезка по границе символа, чтобы не разрывать многобайтовую последовательность UTF-8
+		n := gelfShortMessageLimit
+		for n > 0 && !utf8.RuneStart(short[n]) {
+			n--
+		}
+		short = short[:n]
+	}
+	if short == "" {
+		// short_message обязателен и не может быть пустым
+		short = "-"
+	}
+
+	full := ""
+	if short != r.Message || r.Stack != "" {
+		full = r.Message
+		if r.Stack != "" {
+			full += "\n" + r.Stack
+		}
+	}
+	return short, full
+}
+
+// gelfFieldName приводит ключ к имени дополнительного поля: '_' и символы [\w.-].
+// Поле _id зарезервировано, поэтому переименовывается, а поля, совпадающие
+// со служебными префиксом и местом вызова, получают префикс "fields."
+func gelfFieldName(key string) string {
+	b := []byte(key)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-') {
+			b[i] = '_'
+		}
+	}
+	name := "_" + string(b)
+	switch name {
+	case "_id":
+		name = "_id_"
+	case "_" + PrefixKey, "_" + CallerKey, "_" + FunctionKey:
+		name = "_fields." + name[1:]
+	}
+	return name
+}
+
+// gelfValue приводит значение поля к строке или числу, как требует GELF
+func gelfValue(v interface{}) interface{} {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// GELFConfig параметры назначения GELF
+type GELFConfig struct {
+	// TLS включает TLS поверх TCP
+	TLS *tls.Config
+	// Network тип соединения: "udp" (по умолчанию) или "tcp"
+	Network string
+	// Address адрес входа Graylog
+	Address string
+	// Host имя источника, по умолчанию os.Hostname
+	Host string
+	// Compression сжатие больших сообщений в UDP: "gzip" (по умолчанию), "zlib" или "none"
+	Compression string
+	// ChunkSize максимальный размер UDP-датаграммы, по умолчанию DefaultGELFChunkSize
+	ChunkSize int
+	// Timeout таймаут подключения и записи, по умолчанию DefaultNetworkTimeout
+	Timeout time.Duration
+}
+
+// NewGELFSink создает назначение GELF. Поверх TCP используется NetworkSink
+// с разделением сообщений нулевым байтом; поверх UDP сообщения, не помещающиеся
+// в датаграмму, сжимаются и при необходимости делятся на фрагменты
+func NewGELFSink(cfg GELFConfig) (Sink, error) {
+	encoder := GELFEncoder{Host: cfg.Host}
+	if encoder.Host == "" {
+		encoder.Host, _ = os.Hostname()
+	}
+
+	if strings.HasPrefix(cfg.Network, "tcp") {
+		return NewNetworkSink(NetworkConfig{
+			Network: cfg.Network,
+			Address: cfg.Address,
+			TLS:     cfg.TLS,
+			Encoder: encoder,
+			Framing: FramingNull,
+			Timeout: cfg.Timeout,
+		})
+	}
+
+	if cfg.Network == "" {
+		cfg.Network = "udp"
+	}
+	if !strings.HasPrefix(cfg.Network, "udp") || cfg.TLS != nil {
+		return nil, &ConfigError{Err: fmt.Errorf("неподдерживаемый тип соединения: %s", cfg.Network), Reason: "некорректное назначение GELF"}
+	}
+	switch cfg.Compression {
+	case "":
+		cfg.Compression = GELFCompressGzip
+	case GELFCompressGzip, GELFCompressZlib, GELFCompressNone:
+	default:
+		return nil, &ConfigError{Err: fmt.Errorf("неизвестный алгоритм сжатия: %s", cfg.Compression), Reason: "некорректное назначение GELF"}
+	}
+	if cfg.ChunkSize <= gelfChunkHeader {
+		cfg.ChunkSize = DefaultGELFChunkSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultNetworkTimeout
+	}
+
+	conn, err := net.DialTimeout(cfg.Network, cfg.Address, cfg.Timeout)
+	if err != nil {
+		return nil, &WriteError{Message: "не удалось подключиться к GELF", Cause: err}
+	}
+	return &gelfUDPSink{cfg: cfg, encoder: encoder, conn: conn}, nil
+}
+
+// gelfUDPSink передает сообщения GELF датаграммами
+type gelfUDPSink struct {
+	cfg     GELFConfig
+	encoder GELFEncoder
+	conn    net.Conn
+	mu      sync.Mutex
+}
+
+// Write реализует интерфейс Sink
+func (s *gelfUDPSink) Write(r *Record) error {
+	datagrams, err := s.datagrams([]byte(s.encoder.Encode(r)))
+	if err != nil {
+		return &WriteError{Message: "не удалось подготовить сообщение GELF", Cause: err}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return &WriteError{Message: "соединение с GELF закрыто"}
+	}
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.cfg.Timeout)); err != nil {
+		return &WriteError{Message: "не удалось отправить сообщение GELF", Cause: err}
+	}
+	for _, d := range datagrams {
+		if _, err := s.conn.Write(d); err != nil {
+			return &WriteError{Message: "не удалось отправить сообщение GELF", Cause: err}
+		}
+	}
+	return nil
+}
+
+// datagrams сжимает сообщение, не помещающееся в датаграмму, и делит его на фрагменты
+func (s *gelfUDPSink) datagrams(payload []byte) ([][]byte, error) {
+	if len(payload) <= s.cfg.ChunkSize {
+		return [][]byte{payload}, nil
+	}
+
+	var err error
+	switch s.cfg.Compression {
+	case GELFCompressGzip:
+		payload, err = gzipBytes(payload)
+	case GELFCompressZlib:
+		payload, err = zlibBytes(payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) <= s.cfg.ChunkSize {
+		return [][]byte{payload}, nil
+	}
+
+	size := s.cfg.ChunkSize - gelfChunkHeader
+	count := (len(payload) + size - 1) / size
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("сообщение требует %d фрагментов, допустимо не более %d", count, gelfMaxChunks)
+	}
+
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(payload) {
+			end = len(payload)
+		}
+		chunk := make([]byte, 0, gelfChunkHeader+end-i*size)
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, id[:]...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunks = append(chunks, append(chunk, payload[i*size:end]...))
+	}
+	return chunks, nil
+}
+
+// Close реализует интерфейс Sink
+func (s *gelfUDPSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// zlibBytes сжимает данные zlib
+func zlibBytes(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGELFEncoder(t *testing.T) {
+	r := &Record{
+		Time:    time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC),
+		Level:   ErrorLevel,
+		Prefix:  "API",
+		Message: "request failed\nwith details",
+		Stack:   "goroutine 1 [running]:",
+		Caller:  &Caller{File: "/src/app/main.go", Function: "main.run", Line: 42},
+		Fields: map[string]interface{}{
+			"id":       7,
+			"user id":  "bob",
+			"duration": 1.5,
+			"tags":     []string{"a", "b"},
+			ErrorKey:   errors.New("boom"),
+		},
+	}
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(GELFEncoder{Host: "web-1"}.Encode(r)), &entry))
+
+	assert.Equal(t, "1.1", entry["version"])
+	assert.Equal(t, "web-1", entry["host"])
+	assert.Equal(t, "request failed", entry["short_message"])
+	assert.Equal(t, "request failed\nwith details\ngoroutine 1 [running]:", entry["full_message"])
+	assert.Equal(t, 1714564800.123, entry["timestamp"])
+	assert.Equal(t, float64(3), entry["level"])
+	assert.Equal(t, "API", entry["_prefix"])
+	assert.Equal(t, "app/main.go:42", entry["_caller"])
+	assert.Equal(t, "main.run", entry["_func"])
+	assert.Equal(t, float64(7), entry["_id_"])
+	assert.Equal(t, "bob", entry["_user_id"])
+	assert.Equal(t, 1.5, entry["_duration"])
+	assert.Equal(t, "[a b]", entry["_tags"])
+	assert.Equal(t, "boom", entry["_error"])
+	assert.NotContains(t, entry, "_id")
+
+	entry = nil
+	require.NoError(t, json.Unmarshal([]byte(GELFEncoder{Host: "web-1"}.Encode(&Record{Level: DebugLevel, Message: "short"})), &entry))
+	assert.Equal(t, "short", entry["short_message"])
+	assert.NotContains(t, entry, "full_message")
+	assert.Equal(t, float64(7), entry["level"])
+}
+
+func TestGELFEncoderReservedAndLongMessage(t *testing.T) {
+	r := &Record{
+		Level:   InfoLevel,
+		Prefix:  "API",
+		Message: strings.Repeat("a", gelfShortMessageLimit-1) + "ж",
+		Caller:  &Caller{File: "main.go", Function: "main.run", Line: 1},
+		Fields:  map[string]interface{}{PrefixKey: "user", CallerKey: "x", FunctionKey: "y"},
+	}
+	data := GELFEncoder{Host: "web-1"}.Encode(r)
+	assert.Equal(t, 1, strings.Count(data, `"_prefix"`))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(data), &entry))
+	assert.Equal(t, "API", entry["_prefix"])
+	assert.Equal(t, "user", entry["_fields.prefix"])
+	assert.Equal(t, "main.go:1", entry["_caller"])
+	assert.Equal(t, "x", entry["_fields.caller"])
+	assert.Equal(t, "main.run", entry["_func"])
+	assert.Equal(t, "y", entry["_fields.func"])
+
+	short := entry["short_message"].(string)
+	assert.True(t, utf8.ValidString(short))
+	assert.Equal(t, strings.Repeat("a", gelfShortMessageLimit-1), short)
+	assert.Equal(t, r.Message, entry["full_message"])
+}
+
+func TestGELFSinkUDPChunking(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	sink, err := NewGELFSink(GELFConfig{Address: pc.LocalAddr().String(), Host: "web-1", ChunkSize: 200})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	read := func() []byte {
+		buf := make([]byte, 2048)
+		require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := pc.ReadFrom(buf)
+		require.NoError(t, err)
+		return buf[:n]
+	}
+
+	// Небольшое сообщение передается одной несжатой датаграммой
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: "small"}))
+	assert.Equal(t, byte('{'), read()[0])
+
+	// Случайные данные плохо сжимаются и требуют нескольких фрагментов
+	random := make([]byte, 600)
+	_, err = rand.Read(random)
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: "big", Fields: map[string]interface{}{"payload": hex.EncodeToString(random)}}))
+
+	first := read()
+	require.Equal(t, []byte{0x1e, 0x0f}, first[:2])
+	count := int(first[11])
+	require.Greater(t, count, 1)
+	parts := make([][]byte, count)
+	parts[first[10]] = first[12:]
+	for i := 1; i < count; i++ {
+		chunk := read()
+		assert.LessOrEqual(t, len(chunk), 200)
+		assert.Equal(t, first[2:10], chunk[2:10])
+		parts[chunk[10]] = chunk[12:]
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(bytes.Join(parts, nil)))
+	require.NoError(t, err)
+	payload, err := io.ReadAll(zr)
+	require.NoError(t, err)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &entry))
+	assert.Equal(t, "big", entry["short_message"])
+	assert.Equal(t, hex.EncodeToString(random), entry["_payload"])
+}
+
+func TestGELFSinkUDPCompression(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	sink, err := NewGELFSink(GELFConfig{Address: pc.LocalAddr().String(), Compression: GELFCompressZlib, ChunkSize: 200})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	// Повторяющиеся данные после сжатия помещаются в одну датаграмму
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: strings.Repeat("x", 1000)}))
+	buf := make([]byte, 2048)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	require.Less(t, n, 200)
+
+	zr, err := zlib.NewReader(bytes.NewReader(buf[:n]))
+	require.NoError(t, err)
+	payload, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Contains(t, string(payload), `"short_message":"xxx`)
+
+	// Сообщение, требующее больше 128 фрагментов, отбрасывается с ошибкой
+	s := sink.(*gelfUDPSink)
+	s.cfg.Compression = GELFCompressNone
+	_, err = s.datagrams(make([]byte, 200*gelfMaxChunks))
+	assert.Error(t, err)
+
+	_, err = NewGELFSink(GELFConfig{Address: pc.LocalAddr().String(), Compression: "lz4"})
+	var cfgErr *ConfigError
+	assert.ErrorAs(t, err, &cfgErr)
+}
+
+func TestGELFSinkTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	messages := make(chan string, 8)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			msg, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			messages <- strings.TrimSuffix(msg, "\x00")
+		}
+	}()
+
+	sink, err := NewGELFSink(GELFConfig{Network: "tcp", Address: ln.Addr().String(), Host: "web-1"})
+	require.NoError(t, err)
+
+	l := New().WithSink(sink)
+	l.Info("first")
+	l.Warning("second")
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(receive(t, messages)), &entry))
+	assert.Equal(t, "first", entry["short_message"])
+	require.NoError(t, json.Unmarshal([]byte(receive(t, messages)), &entry))
+	assert.Equal(t, "second", entry["short_message"])
+	assert.Equal(t, float64(4), entry["level"])
+
+	require.NoError(t, l.Close())
+}
@@ -26,35 +26,111 @@ func GetGlobalLogger() ILogger {
 	return globalLogger
 }
 
+// Пакетные функции логирования вызывают внутренние методы *Logger напрямую:
+// так глубина стека совпадает с вызовом методов логгера и место вызова
+// указывает на код пользователя, а не на этот файл
+
 // Debug логирует сообщение на уровне DEBUG
-func Debug(args ...interface{}) { GetGlobalLogger().Debug(args...) }
+func Debug(args ...interface{}) {
+	g := GetGlobalLogger()
+	if l, ok := g.(*Logger); ok {
+		l.log(DebugLevel, args...)
+		return
+	}
+	g.Debug(args...)
+}
 
 // Debugf логирует форматированное сообщение на уровне DEBUG
-func Debugf(format string, args ...interface{}) { GetGlobalLogger().Debugf(format, args...) }
+func Debugf(format string, args ...interface{}) {
+	g := GetGlobalLogger()
+	if l, ok := g.(*Logger); ok {
+		l.logf(DebugLevel, format, args...)
+		return
+	}
+	g.Debugf(format, args...)
+}
 
 // Info логирует сообщение на уровне INFO
-func Info(args ...interface{}) { GetGlobalLogger().Info(args...) }
+func Info(args ...interface{}) {
+	g := GetGlobalLogger()
+	if l, ok := g.(*Logger); ok {
+		l.log(InfoLevel, args...)
+		return
+	}
+	g.Info(args...)
+}
 
 // Infof логирует форматированное сообщение на уровне INFO
-func Infof(format string, args ...interface{}) { GetGlobalLogger().Infof(format, args...) }
+func Infof(format string, args ...interface{}) {
+	g := GetGlobalLogger()
+	if l, ok := g.(*Logger); ok {
+		l.logf(InfoLevel, format, args...)
+		return
+	}
+	g.Infof(format, args...)
+}
 
 // Warning логирует сообщение на уровне WARNING
-func Warning(args ...interface{}) { GetGlobalLogger().Warning(args...) }
+func Warning(args ...interface{}) {
+	g := GetGlobalLogger()
+	if l, ok := g.(*Logger); ok {
+		l.log(WarningLevel, args...)
+		return
+	}
+	g.Warning(args...)
+}
 
 // Warningf логирует форматированное сообщение на уровне WARNING
-func Warningf(format string, args ...interface{}) { GetGlobalLogger().Warningf(format, args...) }
+func Warningf(format string, args ...interface{}) {
+	g := GetGlobalLogger()
+	if l, ok := g.(*Logger); ok {
+		l.logf(WarningLevel, format, args...)
+		return
+	}
+	g.Warningf(format, args...)
+}
 
 // Error логирует сообщение на уровне ERROR
-func Error(args ...interface{}) { GetGlobalLogger().Error(args...) }
+func Error(args ...interface{}) {
+	g := GetGlobalLogger()
+	if l, ok := g.(*Logger); ok {
+		l.log(ErrorLevel, args...)
+		return
+	}
+	g.Error(args...)
+}
 
 // Errorf логирует форматированное сообщение на уровне ERROR
-func Errorf(format string, args ...interface{}) { GetGlobalLogger().Errorf(format, args...) }
+func Errorf(format string, args ...interface{}) {
+	g := GetGlobalLogger()
+	if l, ok := g.(*Logger); ok {
+		l.logf(ErrorLevel, format, args...)
+		return
+	}
+	g.Errorf(format, args...)
+}
 
 // Fatal логирует сообщение на уровне FATAL и завершает программу
-func Fatal(args ...interface{}) { GetGlobalLogger().Fatal(args...) }
+func Fatal(args ...interface{}) {
+	g := GetGlobalLogger()
+	if l, ok := g.(*Logger); ok {
+		l.log(FatalLevel, args...)
+		l.exit()
+		return
+	}
+	g.Fatal(args...)
+}
 
 // Fatalf логирует форматированное сообщение на уровне FATAL и завершает программу
-func Fatalf(format string, args ...interface{}) { GetGlobalLogger().Fatalf(format, args...) }
+func Fatalf(format string, args ...interface{}) {
+	g := GetGlobalLogger()
+	if l, ok := g.(*Logger); ok {
+		l.logf(FatalLevel, format, args...)
+		l.exit()
+		return
+	}
+	g.Fatalf(format, args...)
+}
 
 // WithPrefix создает новый логгер с указанным префиксом
 func WithPrefix(prefix string) ILogger { return GetGlobalLogger().WithPrefix(prefix) }
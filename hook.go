@@ -0,0 +1,210 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultHookBuffer размер очереди асинхронного хука по умолчанию
+const DefaultHookBuffer = 256
+
+// Hook получает каждую записанную запись выбранных уровней. Используется для
+// подсчета метрик, отправки оповещений и других действий, не требующих отдельного
+// назначения вывода. Хуки не должны изменять переданную запись
+type Hook interface {
+	// Levels возвращает уровни, для которых вызывается хук; пустой список означает все уровни
+	Levels() []Level
+	// Fire обрабатывает запись
+	Fire(r *Record) error
+}
+
+// funcHook хук на основе функции
+type funcHook struct {
+	fn     func(r *Record) error
+	levels []Level
+}
+
+// NewHook создает хук из функции. Без указания уровней хук вызывается для всех записей
+func NewHook(fn func(r *Record) error, levels ...Level) Hook {
+	return &funcHook{fn: fn, levels: levels}
+}
+
+// Levels реализует интерфейс Hook
+func (h *funcHook) Levels() []Level {
+	return h.levels
+}
+
+// Fire реализует интерфейс Hook
+func (h *funcHook) Fire(r *Record) error {
+	return h.fn(r)
+}
+
+// hookEntry зарегистрированный хук с предвычисленной маской уровней
+type hookEntry struct {
+	hook   Hook
+	async  *asyncHook
+	owner  *hookOwner
+	levels [FatalLevel + 1]bool
+}
+
+// hookOwner отмечает логгеры, владеющие асинхронными хуками: логгер, возвращенный
+// WithAsyncHook, и следующие WithAsyncHook, вызванные на нем. Остальные производные
+// логгеры отметки не получают, поэтому их Close не останавливает хуки
+type hookOwner struct {
+	_ byte // ненулевой размер, чтобы указатели на разные отметки не совпадали
+}
+
+// newHookEntry создает запись о хуке
+func newHookEntry(h Hook) *hookEntry {
+	e := &hookEntry{hook: h}
+	levels := h.Levels()
+	for lv := DebugLevel; lv <= FatalLevel; lv++ {
+		e.levels[lv] = len(levels) == 0
+	}
+	for _, lv := range levels {
+		if lv >= DebugLevel && lv <= FatalLevel {
+			e.levels[lv] = true
+		}
+	}
+	return e
+}
+
+// accepts сообщает, подписан ли хук на уровень
+func (e *hookEntry) accepts(level Level) bool {
+	return level >= DebugLevel && level <= FatalLevel && e.levels[level]
+}
+
+// asyncHook выполняет хук в отдельной горутине через ограниченную очередь.
+// При переполнении очереди записи отбрасываются, чтобы не блокировать логирование
+type asyncHook struct {
+	dropped uint64 // первое поле для выравнивания атомарного счетчика на 32-битных платформах
+	queue   chan *Record
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+}
+
+// newAsyncHook запускает горутину обработки очереди хука
+func newAsyncHook(h Hook, buffer int) *asyncHook {
+	if buffer <= 0 {
+		buffer = DefaultHookBuffer
+	}
+	a := &asyncHook{
+		queue: make(chan *Record, buffer),
+		done:  make(chan struct{}),
+	}
+	go func() {
+		defer close(a.done)
+		for r := range a.queue {
+			fireHook(h, r)
+		}
+	}()
+	return a
+}
+
+// enqueue ставит запись в очередь без блокировки
+func (a *asyncHook) enqueue(r *Record) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return
+	}
+	select {
+	case a.queue <- r:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+}
+
+// close останавливает прием записей и дожидается обработки очереди
+func (a *asyncHook) close() {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+	<-a.done
+}
+
+// fireHook вызывает хук, изолируя логгер от паник и ошибок в нем
+func fireHook(h Hook, r *Record) {
+	defer func() {
+		if p := recover(); p != nil {
+			reportHookError(fmt.Errorf("паника в хуке: %v", p))
+		}
+	}()
+	if err := h.Fire(r); err != nil {
+		reportHookError(err)
+	}
+}
+
+// reportHookError сообщает об ошибке хука в stderr, не используя сам логгер
+func reportHookError(err error) {
+	fmt.Fprintf(os.Stderr, "ошибка хука логгера: %v\n", err)
+}
+
+// fireHooks передает запись всем хукам логгера, подписанным на ее уровень
+func (l *Logger) fireHooks(r *Record) {
+	for _, e := range l.hooks {
+		if !e.accepts(r.Level) {
+			continue
+		}
+		if e.async != nil {
+			e.async.enqueue(r)
+			continue
+		}
+		fireHook(e.hook, r)
+	}
+}
+
+// closeHooks дожидается обработки очередей асинхронных хуков, которыми владеет
+// этот логгер, или, если all, всех хуков логгера
+func (l *Logger) closeHooks(all bool) {
+	for _, e := range l.hooks {
+		if e.async != nil && (all || l.hookOwner != nil && e.owner == l.hookOwner) {
+			e.async.close()
+		}
+	}
+}
+
+// HookDropped возвращает количество записей, отброшенных при переполнении
+// очередей асинхронных хуков логгера
+func (l *Logger) HookDropped() uint64 {
+	var dropped uint64
+	for _, e := range l.hooks {
+		if e.async != nil {
+			dropped += atomic.LoadUint64(&e.async.dropped)
+		}
+	}
+	return dropped
+}
+
+// WithHook создает новый логгер с синхронным хуком. Хук вызывается в горутине,
+// выполняющей запись, до вывода записи. Хуки наследуются дочерними логгерами
+func (l *Logger) WithHook(h Hook) *Logger {
+	newLogger := l.clone()
+	newLogger.hooks = append(append([]*hookEntry{}, l.hooks...), newHookEntry(h))
+	return newLogger
+}
+
+// WithAsyncHook создает новый логгер с асинхронным хуком, обрабатывающим записи
+// в отдельной горутине через очередь размером buffer. Close возвращенного логгера
+// дожидается обработки очереди; Close производных от него логгеров хук не останавливает
+func (l *Logger) WithAsyncHook(h Hook, buffer int) *Logger {
+	owner := l.hookOwner
+	if owner == nil {
+		owner = &hookOwner{}
+	}
+	e := newHookEntry(h)
+	e.async = newAsyncHook(h, buffer)
+	e.owner = owner
+
+	newLogger := l.clone()
+	newLogger.hooks = append(append([]*hookEntry{}, l.hooks...), e)
+	newLogger.hookOwner = owner
+	return newLogger
+}
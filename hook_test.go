@@ -0,0 +1,148 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHook сохраняет полученные записи
+type recordingHook struct {
+	mu      sync.Mutex
+	records []*Record
+	levels  []Level
+}
+
+func (h *recordingHook) Levels() []Level { return h.levels }
+
+func (h *recordingHook) Fire(r *Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *recordingHook) Records() []*Record {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]*Record{}, h.records...)
+}
+
+func TestHookReceivesRecord(t *testing.T) {
+	hook := &recordingHook{levels: []Level{ErrorLevel, FatalLevel}}
+	l := New().WithHook(hook)
+	child := l.WithPrefix("API").WithFields(map[string]interface{}{"id": 7})
+
+	child.Info("ignored")
+	child.Errorf("failed %d", 1)
+
+	records := hook.Records()
+	require.Len(t, records, 1)
+	assert.Equal(t, ErrorLevel, records[0].Level)
+	assert.Equal(t, "API", records[0].Prefix)
+	assert.Equal(t, "failed 1", records[0].Message)
+	assert.Equal(t, 7, records[0].Fields["id"])
+	assert.False(t, records[0].Time.IsZero())
+}
+
+func TestHookPanicIsolation(t *testing.T) {
+	calls := 0
+	panicking := NewHook(func(r *Record) error { panic("boom") })
+	failing := NewHook(func(r *Record) error { return errors.New("hook failed") })
+	counting := NewHook(func(r *Record) error {
+		calls++
+		return nil
+	}, InfoLevel)
+
+	l := New().WithHook(panicking).WithHook(failing).WithHook(counting)
+	assert.NotPanics(t, func() {
+		l.Info("still logged")
+		l.Debug("not counted")
+	})
+
+	assert.Equal(t, 1, calls)
+	assert.Len(t, l.GetMessages(), 2)
+}
+
+func TestAsyncHook(t *testing.T) {
+	hook := &recordingHook{}
+	l := New().WithAsyncHook(hook, 16)
+	child := l.WithPrefix("WORKER")
+
+	for i := 0; i < 10; i++ {
+		child.Infof("job %d", i)
+	}
+	l.WithAsyncHook(NewHook(func(r *Record) error { panic("async boom") }), 1).Info("isolated")
+
+	// Close дожидается обработки очереди
+	require.NoError(t, l.Close())
+	assert.Len(t, hook.Records(), 11)
+
+	// После закрытия записи в очередь не попадают и паники не возникает
+	assert.NotPanics(t, func() { child.Info("after close") })
+	assert.Len(t, hook.Records(), 11)
+}
+
+func TestAsyncHookOwnership(t *testing.T) {
+	hook := &recordingHook{}
+	l := New().WithAsyncHook(hook, 16)
+	child := l.WithPrefix("WORKER")
+
+	// Закрытие производных логгеров не останавливает хук родителя
+	for _, derived := range []ILogger{
+		child,
+		l.WithLevel("debug"),
+		l.WithTimeFormat(time.RFC3339),
+		l.WithConfig(DefaultConfig()),
+		l.WithFields(Fields{"k": "v"}),
+	} {
+		require.NoError(t, derived.Close())
+	}
+	l.Info("after child close")
+	require.NoError(t, l.Close())
+	require.Len(t, hook.Records(), 1)
+	assert.Equal(t, "after child close", hook.Records()[0].Message)
+
+	// Хук, зарегистрированный дочерним логгером, закрывается им самим
+	own := &recordingHook{}
+	scoped := New().WithPrefix("JOB").(*Logger).WithAsyncHook(own, 16)
+	scoped.Info("scoped")
+	require.NoError(t, scoped.Close())
+	assert.Len(t, own.Records(), 1)
+}
+
+func TestAsyncHookDropped(t *testing.T) {
+	release := make(chan struct{})
+	blocking := NewHook(func(r *Record) error {
+		<-release
+		return nil
+	})
+	l := New().WithAsyncHook(blocking, 1)
+
+	for i := 0; i < 5; i++ {
+		l.Info("burst")
+	}
+	// Одна запись обрабатывается, одна ждет в очереди, остальные отброшены
+	assert.GreaterOrEqual(t, l.HookDropped(), uint64(3))
+	close(release)
+	require.NoError(t, l.Close())
+}
+
+func TestHookReceivesSummaries(t *testing.T) {
+	hook := &recordingHook{levels: []Level{ErrorLevel}}
+	l := New().WithHook(hook).WithDeduplicator(NewDeduplicator(time.Minute))
+
+	for i := 0; i < 3; i++ {
+		l.Error("disk full")
+	}
+	require.NoError(t, l.Close())
+
+	records := hook.Records()
+	require.Len(t, records, 2)
+	assert.Equal(t, "disk full", records[0].Message)
+	assert.Equal(t, "repeated 2 times", records[1].Message)
+}
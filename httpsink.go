@@ -0,0 +1,413 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Параметры повторных попыток по умолчанию
+const (
+	DefaultMaxRetries  = 3
+	DefaultMinBackoff  = 500 * time.Millisecond
+	DefaultMaxBackoff  = 30 * time.Second
+	DefaultHTTPTimeout = 10 * time.Second
+)
+
+// maxErrorBody объем тела ответа, сохраняемый в HTTPStatusError
+const maxErrorBody = 1024
+
+// errRetriesAborted повторы прерваны закрытием назначения
+var errRetriesAborted = fmt.Errorf("повторы прерваны при закрытии назначения")
+
+// RetryConfig параметры повторной отправки при временных ошибках
+type RetryConfig struct {
+	// MaxRetries количество повторов, по умолчанию DefaultMaxRetries; отрицательное значение отключает повторы
+	MaxRetries int
+	// MinBackoff начальная пауза между попытками, по умолчанию DefaultMinBackoff
+	MinBackoff time.Duration
+	// MaxBackoff максимальная пауза между попытками, по умолчанию DefaultMaxBackoff
+	MaxBackoff time.Duration
+}
+
+// withDefaults возвращает параметры с подставленными значениями по умолчанию
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.MaxRetries == 0 {
+		c.MaxRetries = DefaultMaxRetries
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = DefaultMinBackoff
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		c.MaxBackoff = DefaultMaxBackoff
+		if c.MaxBackoff < c.MinBackoff {
+			c.MaxBackoff = c.MinBackoff
+		}
+	}
+	return c
+}
+
+// HTTPStatusError ответ сервера с неуспешным кодом
+type HTTPStatusError struct {
+	Body       string
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("сервер вернул %d", e.StatusCode)
+	}
+	return fmt.Sprintf("сервер вернул %d: %s", e.StatusCode, e.Body)
+}
+
+// LogFields возвращает структурированные поля ошибки ответа
+func (e *HTTPStatusError) LogFields() map[string]interface{} {
+	return map[string]interface{}{"status": e.StatusCode}
+}
+
+// retryable сообщает, имеет ли смысл повторить запрос с этим кодом ответа
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// httpPoster отправляет запросы с повторами при сетевых ошибках и ответах 5xx и 429
+type httpPoster struct {
+	retries uint64 // первое поле для выравнивания атомарного счетчика на 32-битных платформах
+	client  *http.Client
+	retry   RetryConfig
+	// sleep выдерживает паузу между попытками; false означает, что повторы прерваны
+	sleep   func(time.Duration) bool
+	abort   chan struct{}
+	stopped sync.Once
+}
+
+// newHTTPPoster создает отправителя; nil client заменяется клиентом с DefaultHTTPTimeout
+func newHTTPPoster(client *http.Client, retry RetryConfig) *httpPoster {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	p := &httpPoster{client: client, retry: retry.withDefaults(), abort: make(chan struct{})}
+	p.sleep = p.pause
+	return p
+}
+
+// pause выдерживает паузу перед повтором; возвращает false, если повторы прерваны вызовом stop
+func (p *httpPoster) pause(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-p.abort:
+		return false
+	}
+}
+
+// stop прерывает текущую и все последующие паузы между попытками
+func (p *httpPoster) stop() {
+	p.stopped.Do(func() { close(p.abort) })
+}
+
+// closeTimeout время, в течение которого при закрытии продолжаются повторы
+func (p *httpPoster) closeTimeout() time.Duration {
+	if p.client.Timeout > 0 {
+		return p.client.Timeout
+	}
+	return DefaultHTTPTimeout
+}
+
+// do выполняет запрос, созданный build, и возвращает тело успешного ответа.
+// build вызывается для каждой попытки, так как тело запроса читается однократно.
+// Пауза между попытками растет экспоненциально; заголовок Retry-After имеет приоритет,
+// но ограничивается MaxBackoff
+func (p *httpPoster) do(build func() (*http.Request, error)) ([]byte, error) {
+	backoff := p.retry.MinBackoff
+	for attempt := 0; ; attempt++ {
+		req, err := build()
+		if err != nil {
+			return nil, err
+		}
+
+		body, wait, err := p.attempt(req)
+		if err == nil {
+			return body, nil
+		}
+		if wait < 0 || attempt >= p.retry.MaxRetries {
+			return nil, err
+		}
+
+		if wait == 0 {
+			wait = backoff
+			if backoff *= 2; backoff > p.retry.MaxBackoff {
+				backoff = p.retry.MaxBackoff
+			}
+		}
+		if wait > p.retry.MaxBackoff {
+			wait = p.retry.MaxBackoff
+		}
+		if !p.sleep(wait) {
+			return nil, err
+		}
+		atomic.AddUint64(&p.retries, 1)
+	}
+}
+
+// attempt выполняет одну попытку. Возвращает паузу перед повтором: 0 означает
+// паузу по умолчанию, отрицательное значение означает, что повтор бесполезен
+func (p *httpPoster) attempt(req *http.Request) ([]byte, time.Duration, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return body, 0, nil
+	}
+
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody]
+	}
+	statusErr := &HTTPStatusError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(body))}
+	if !retryable(resp.StatusCode) {
+		return nil, -1, statusErr
+	}
+	return nil, parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()), statusErr
+}
+
+// parseRetryAfter разбирает заголовок Retry-After в секундах или в виде даты HTTP.
+// Возвращает 0, если заголовок отсутствует или некорректен
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// Retries возвращает количество выполненных повторов
+func (p *httpPoster) Retries() uint64 {
+	return atomic.LoadUint64(&p.retries)
+}
+
+// gzipBytes сжимает данные gzip
+func gzipBytes(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// newBodyRequest создает функцию построения запроса с телом, при необходимости сжатым gzip
+func newBodyRequest(method, url, contentType string, headers map[string]string, body []byte, compress bool) (func() (*http.Request, error), error) {
+	if compress {
+		compressed, err := gzipBytes(body)
+		if err != nil {
+			return nil, err
+		}
+		body = compressed
+	}
+	return func() (*http.Request, error) {
+		req, err := http.NewRequest(method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", contentType)
+		if compress {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return req, nil
+	}, nil
+}
+
+// batchSender общая часть назначений, отправляющих пакеты записей HTTP-запросами:
+// накопление пакетов, повторы и счетчики. Конкретное назначение задает построение
+// запроса и, при необходимости, разбор ответа с ошибками отдельных записей
+type batchSender struct {
+	sent    uint64 // первые поля для выравнивания атомарных счетчиков на 32-битных платформах
+	failed  uint64
+	batches uint64
+	name    string
+	poster  *httpPoster
+	batcher *batcher
+	// request строит запрос для пакета
+	request func(batch []batchEntry) (func() (*http.Request, error), error)
+	// response разбирает тело успешного ответа и возвращает количество
+	// отвергнутых сервером записей; nil означает, что принят весь пакет.
+	// Отрицательное значение означает, что результат будет учтен позже вызовом settle
+	response func(body []byte, batch []batchEntry) (int, error)
+}
+
+// newBatchSender создает отправителя и запускает накопление пакетов
+func newBatchSender(name string, batch BatchConfig, client *http.Client, retry RetryConfig,
+	request func([]batchEntry) (func() (*http.Request, error), error)) *batchSender {
+	s := &batchSender{name: name, poster: newHTTPPoster(client, retry), request: request}
+	s.batcher = newBatcher(batch, s.flush)
+	return s
+}
+
+// add добавляет запись в пакет
+func (s *batchSender) add(e batchEntry) error {
+	if !s.batcher.add(e) {
+		return &WriteError{Message: "назначение " + s.name + " закрыто"}
+	}
+	return nil
+}
+
+// flush отправляет пакет и учитывает результат
+func (s *batchSender) flush(batch []batchEntry) {
+	atomic.AddUint64(&s.batches, 1)
+
+	build, err := s.request(batch)
+	var body []byte
+	if err == nil {
+		body, err = s.poster.do(build)
+	}
+	if err != nil {
+		atomic.AddUint64(&s.failed, uint64(len(batch)))
+		reportSinkError(s.name, err)
+		return
+	}
+
+	rejected := 0
+	if s.response != nil {
+		if rejected, err = s.response(body, batch); err != nil {
+			reportSinkError(s.name, err)
+		}
+	}
+	if rejected >= 0 {
+		s.settle(len(batch)-rejected, rejected)
+	}
+}
+
+// settle учитывает доставленные и отвергнутые записи
+func (s *batchSender) settle(sent, failed int) {
+	atomic.AddUint64(&s.failed, uint64(failed))
+	atomic.AddUint64(&s.sent, uint64(sent))
+}
+
+// Stats возвращает счетчики назначения
+func (s *batchSender) Stats() HTTPStats {
+	return HTTPStats{
+		SentRecords:    atomic.LoadUint64(&s.sent),
+		DroppedRecords: atomic.LoadUint64(&s.failed) + s.batcher.Dropped(),
+		Batches:        atomic.LoadUint64(&s.batches),
+		Retries:        s.poster.Retries(),
+	}
+}
+
+// Close отправляет накопленные записи. Если отправка с повторами не завершилась
+// за таймаут HTTP-клиента, паузы между повторами прерываются, а неотправленные
+// записи отбрасываются; в этом случае возвращается ошибка
+func (s *batchSender) Close() error {
+	before := s.Stats().DroppedRecords
+	closed := make(chan struct{})
+	go func() {
+		s.batcher.close()
+		close(closed)
+	}()
+
+	timer := time.NewTimer(s.poster.closeTimeout())
+	defer timer.Stop()
+	select {
+	case <-closed:
+	case <-timer.C:
+		s.poster.stop()
+		<-closed
+	}
+
+	if dropped := s.Stats().DroppedRecords - before; dropped > 0 {
+		return &WriteError{Message: fmt.Sprintf("назначение %s: отброшено записей: %d", s.name, dropped)}
+	}
+	return nil
+}
+
+// HTTPConfig параметры HTTP-назначения
+type HTTPConfig struct {
+	BatchConfig
+	RetryConfig
+	// Client HTTP-клиент, по умолчанию клиент с таймаутом DefaultHTTPTimeout
+	Client *http.Client
+	// Headers дополнительные заголовки запросов, например Authorization
+	Headers map[string]string
+	// Encoder кодировщик записей, по умолчанию JSONEncoder
+	Encoder Encoder
+	// URL адрес приема записей
+	URL string
+	// ContentType тип содержимого, по умолчанию "application/x-ndjson"
+	ContentType string
+	// Gzip включает сжатие тела запроса
+	Gzip bool
+}
+
+// HTTPStats счетчики назначений, отправляющих записи HTTP-запросами
+type HTTPStats struct {
+	SentRecords    uint64
+	DroppedRecords uint64
+	Batches        uint64
+	Retries        uint64
+}
+
+// HTTPSink отправляет записи пакетами методом POST, по одной записи на строку
+// (NDJSON). Пакеты отправляются в фоне; Close отправляет накопленные записи
+type HTTPSink struct {
+	*batchSender
+	cfg HTTPConfig
+}
+
+// NewHTTPSink создает HTTP-назначение
+func NewHTTPSink(cfg HTTPConfig) (*HTTPSink, error) {
+	if cfg.URL == "" {
+		return nil, &ConfigError{Err: fmt.Errorf("не указан URL"), Reason: "некорректное HTTP-назначение"}
+	}
+	if cfg.Encoder == nil {
+		cfg.Encoder = JSONEncoder{}
+	}
+	if cfg.ContentType == "" {
+		cfg.ContentType = "application/x-ndjson"
+	}
+
+	s := &HTTPSink{cfg: cfg}
+	s.batchSender = newBatchSender("http", cfg.BatchConfig, cfg.Client, cfg.RetryConfig, s.request)
+	return s, nil
+}
+
+// Write реализует интерфейс Sink
+func (s *HTTPSink) Write(r *Record) error {
+	return s.add(batchEntry{record: r, data: []byte(s.cfg.Encoder.Encode(r) + "\n")})
+}
+
+// request объединяет строки пакета в тело запроса
+func (s *HTTPSink) request(batch []batchEntry) (func() (*http.Request, error), error) {
+	var body bytes.Buffer
+	for _, e := range batch {
+		body.Write(e.data)
+	}
+	return newBodyRequest(http.MethodPost, s.cfg.URL, s.cfg.ContentType, s.cfg.Headers, body.Bytes(), s.cfg.Gzip)
+}
@@ -0,0 +1,248 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectingServer сохраняет тела полученных запросов
+type collectingServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []string
+	status []int
+	header http.Header
+}
+
+// newCollectingServer запускает сервер, отвечающий кодами из status по порядку, затем 200
+func newCollectingServer(t *testing.T, status ...int) *collectingServer {
+	s := &collectingServer{status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			reader = zr
+		}
+		body, _ := io.ReadAll(reader)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.header = r.Header.Clone()
+		if len(s.status) > 0 {
+			code := s.status[0]
+			s.status = s.status[1:]
+			if code == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "7")
+			}
+			w.WriteHeader(code)
+			return
+		}
+		s.bodies = append(s.bodies, string(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Bodies возвращает тела успешно принятых запросов
+func (s *collectingServer) Bodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.bodies...)
+}
+
+// Header возвращает заголовки последнего запроса
+func (s *collectingServer) Header() http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.header
+}
+
+// countLines считает строки в теле NDJSON
+func countLines(body string) int {
+	n := 0
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		n++
+	}
+	return n
+}
+
+func TestHTTPSinkBatchesByCount(t *testing.T) {
+	srv := newCollectingServer(t)
+	sink, err := NewHTTPSink(HTTPConfig{
+		URL:         srv.URL,
+		BatchConfig: BatchConfig{MaxRecords: 2, Interval: time.Hour},
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: "m"}))
+	}
+	require.NoError(t, sink.Close())
+
+	bodies := srv.Bodies()
+	require.Len(t, bodies, 3)
+	assert.Equal(t, 2, countLines(bodies[0]))
+	assert.Equal(t, 1, countLines(bodies[2]))
+	assert.Equal(t, "Bearer token", srv.Header().Get("Authorization"))
+	assert.Equal(t, "application/x-ndjson", srv.Header().Get("Content-Type"))
+	assert.Equal(t, HTTPStats{SentRecords: 5, Batches: 3}, sink.Stats())
+	assert.Error(t, sink.Write(&Record{Message: "late"}))
+}
+
+func TestHTTPSinkBatchesByInterval(t *testing.T) {
+	srv := newCollectingServer(t)
+	sink, err := NewHTTPSink(HTTPConfig{URL: srv.URL, BatchConfig: BatchConfig{Interval: 20 * time.Millisecond}})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: "tick"}))
+	assert.Eventually(t, func() bool { return len(srv.Bodies()) == 1 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestHTTPSinkBatchesBySize(t *testing.T) {
+	srv := newCollectingServer(t)
+	sink, err := NewHTTPSink(HTTPConfig{
+		URL:         srv.URL,
+		Encoder:     TextEncoder{},
+		BatchConfig: BatchConfig{MaxBytes: 100, Interval: time.Hour},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: strings.Repeat("x", 40)}))
+	}
+	require.NoError(t, sink.Close())
+	assert.Len(t, srv.Bodies(), 4)
+}
+
+func TestHTTPSinkGzip(t *testing.T) {
+	srv := newCollectingServer(t)
+	sink, err := NewHTTPSink(HTTPConfig{URL: srv.URL, Gzip: true})
+	require.NoError(t, err)
+
+	l := New().WithSink(sink)
+	l.Info("compressed")
+	require.NoError(t, l.Close())
+
+	require.Len(t, srv.Bodies(), 1)
+	assert.Contains(t, srv.Bodies()[0], `"msg":"compressed"`)
+	assert.Equal(t, "gzip", srv.Header().Get("Content-Encoding"))
+}
+
+func TestHTTPSinkRetries(t *testing.T) {
+	srv := newCollectingServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	sink, err := NewHTTPSink(HTTPConfig{URL: srv.URL, RetryConfig: RetryConfig{MinBackoff: 10 * time.Millisecond}})
+	require.NoError(t, err)
+
+	var waits []time.Duration
+	sink.poster.sleep = func(d time.Duration) bool {
+		waits = append(waits, d)
+		return true
+	}
+
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: "retried"}))
+	require.NoError(t, sink.Close())
+
+	assert.Len(t, srv.Bodies(), 1)
+	assert.Equal(t, []time.Duration{10 * time.Millisecond, 7 * time.Second}, waits)
+	assert.Equal(t, uint64(2), sink.Stats().Retries)
+}
+
+func TestHTTPSinkGivesUp(t *testing.T) {
+	srv := newCollectingServer(t, http.StatusBadRequest, http.StatusInternalServerError, http.StatusInternalServerError)
+	sink, err := NewHTTPSink(HTTPConfig{URL: srv.URL, RetryConfig: RetryConfig{MaxRetries: 1}})
+	require.NoError(t, err)
+	sink.poster.sleep = func(time.Duration) bool { return true }
+
+	// Ответ 400 не повторяется, ответы 500 повторяются не более MaxRetries раз
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: "bad"}))
+	sink.batcher.mu.Lock()
+	sink.batcher.cut(true)
+	sink.batcher.mu.Unlock()
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: "down"}))
+	var writeErr *WriteError
+	assert.ErrorAs(t, sink.Close(), &writeErr)
+
+	stats := sink.Stats()
+	assert.Equal(t, uint64(2), stats.DroppedRecords)
+	assert.Equal(t, uint64(1), stats.Retries)
+	assert.Empty(t, srv.Bodies())
+}
+
+func TestHTTPSinkCloseAbortsLongRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "86400")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	sink, err := NewHTTPSink(HTTPConfig{
+		URL:         srv.URL,
+		Client:      &http.Client{Timeout: 50 * time.Millisecond},
+		RetryConfig: RetryConfig{MaxRetries: 100, MinBackoff: time.Millisecond, MaxBackoff: time.Hour},
+	})
+	require.NoError(t, err)
+
+	var waits []time.Duration
+	pause := sink.poster.sleep
+	sink.poster.sleep = func(d time.Duration) bool {
+		waits = append(waits, d)
+		return pause(d)
+	}
+
+	// Пауза Retry-After прерывается при закрытии, Close не ждет сутки
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: "stuck"}))
+	start := time.Now()
+	var writeErr *WriteError
+	assert.ErrorAs(t, sink.Close(), &writeErr)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, uint64(1), sink.Stats().DroppedRecords)
+	require.NotEmpty(t, waits)
+	assert.Equal(t, time.Hour, waits[0])
+
+	// Retry-After не превышает MaxBackoff
+	p := newHTTPPoster(nil, RetryConfig{MaxRetries: 1, MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
+	waits = nil
+	p.sleep = func(d time.Duration) bool {
+		waits = append(waits, d)
+		return true
+	}
+	_, err = p.do(func() (*http.Request, error) { return http.NewRequest(http.MethodPost, srv.URL, nil) })
+	assert.Error(t, err)
+	assert.Equal(t, []time.Duration{10 * time.Millisecond}, waits)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, time.Minute, parseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+	assert.Zero(t, parseRetryAfter("", now))
+}
+
+func TestGzipBytes(t *testing.T) {
+	data, err := gzipBytes([]byte("payload"))
+	require.NoError(t, err)
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	plain, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, "payload", string(plain))
+}
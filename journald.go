@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+// DefaultJournaldSocket путь к сокету нативного протокола journald
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldConfig параметры назначения journald
+type JournaldConfig struct {
+	// Path путь к сокету journald, по умолчанию DefaultJournaldSocket
+	Path string
+	// Identifier значение SYSLOG_IDENTIFIER; по умолчанию префикс записи или DefaultPrefix
+	Identifier string
+}
+
+// encodeJournald кодирует запись в датаграмму нативного протокола journald.
+// Уровень передается в PRIORITY, префикс в SYSLOG_IDENTIFIER, место вызова
+// в CODE_FILE, CODE_LINE и CODE_FUNC, поля записи в поля журнала в верхнем регистре
+func encodeJournald(r *Record, identifier string) []byte {
+	if identifier == "" {
+		identifier = r.Prefix
+	}
+	if identifier == "" {
+		identifier = DefaultPrefix
+	}
+
+	var b bytes.Buffer
+	writeJournaldField(&b, "MESSAGE", r.Message)
+	writeJournaldField(&b, "PRIORITY", strconv.Itoa(syslogSeverity(r.Level)))
+	writeJournaldField(&b, "SYSLOG_IDENTIFIER", identifier)
+	if r.Caller != nil {
+		writeJournaldField(&b, "CODE_FILE", r.Caller.File)
+		writeJournaldField(&b, "CODE_LINE", strconv.Itoa(r.Caller.Line))
+		if r.Caller.Function != "" {
+			writeJournaldField(&b, "CODE_FUNC", r.Caller.Function)
+		}
+	}
+	if r.Stack != "" {
+		writeJournaldField(&b, strings.ToUpper(StacktraceKey), r.Stack)
+	}
+
+	// Место вызова уже передано в полях CODE_*
+	fields := *r
+	fields.Caller = nil
+	for _, p := range flatFields(&fields) {
+		if name := journaldFieldName(p.Key); name != "" {
+			writeJournaldField(&b, name, p.Value)
+		}
+	}
+	return b.Bytes()
+}
+
+// writeJournaldField добавляет поле в датаграмму. Значения без перевода строки
+// записываются как KEY=value, остальные в двоичном виде с длиной в little-endian
+func writeJournaldField(b *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		b.WriteString(name + "=" + value + "\n")
+		return
+	}
+	b.WriteString(name + "\n")
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	b.Write(size[:])
+	b.WriteString(value + "\n")
+}
+
+// journaldFieldName приводит ключ к имени поля журнала: заглавные латинские буквы,
+// цифры и '_', не более 64 символов, без ведущего '_' (такие поля зарезервированы
+// за journald) и без ведущей цифры. Возвращает пустую строку для неприводимых ключей
+func journaldFieldName(key string) string {
+	b := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	name := strings.TrimLeft(string(b), "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "F_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
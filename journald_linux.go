@@ -0,0 +1,197 @@
+//go:build linux
+
+package logger
+
+import (
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// memfdCreateTrap номера системного вызова memfd_create, отсутствующего
+// в пакете syscall для части архитектур
+var memfdCreateTrap = map[string]uintptr{
+	"386":      356,
+	"amd64":    319,
+	"arm":      385,
+	"arm64":    279,
+	"loong64":  279,
+	"mips":     4354,
+	"mipsle":   4354,
+	"mips64":   5314,
+	"mips64le": 5314,
+	"ppc64":    360,
+	"ppc64le":  360,
+	"riscv64":  279,
+	"s390x":    350,
+}[runtime.GOARCH]
+
+// Флаги memfd_create и запечатывания, которых нет в пакете syscall
+const (
+	mfdCloexec       = 0x1
+	mfdAllowSealing  = 0x2
+	fcntlAddSeals    = 1033
+	sealAll          = 0x1 | 0x2 | 0x4 | 0x8 // F_SEAL_SEAL | F_SEAL_SHRINK | F_SEAL_GROW | F_SEAL_WRITE
+	journaldShmDir   = "/dev/shm"
+	journaldMemfdTag = "logger-journal"
+)
+
+// JournaldSink передает записи в systemd-journald по нативному протоколу,
+// сохраняя поля записи отдельными полями журнала. Записи, не помещающиеся
+// в датаграмму, передаются через запечатанный memfd
+type JournaldSink struct {
+	cfg    JournaldConfig
+	conn   *net.UnixConn
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewJournaldSink создает назначение journald
+func NewJournaldSink(cfg JournaldConfig) (*JournaldSink, error) {
+	if cfg.Path == "" {
+		cfg.Path = DefaultJournaldSocket
+	}
+
+	s := &JournaldSink{cfg: cfg}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect подключается к сокету journald
+func (s *JournaldSink) connect() error {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: s.cfg.Path, Net: "unixgram"})
+	if err != nil {
+		return &WriteError{Message: "сокет journald недоступен", Cause: err}
+	}
+	s.conn = conn
+	return nil
+}
+
+// Write реализует интерфейс Sink. При потере соединения, например после
+// перезапуска journald, сокет подключается заново
+func (s *JournaldSink) Write(r *Record) error {
+	data := encodeJournald(r, s.cfg.Identifier)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return &WriteError{Message: "соединение с journald закрыто"}
+	}
+	if s.conn != nil {
+		err := s.send(data)
+		if !journaldDisconnected(err) {
+			return err
+		}
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	if err := s.connect(); err != nil {
+		return err
+	}
+	err := s.send(data)
+	if journaldDisconnected(err) {
+		_ = s.conn.Close()
+		s.conn = nil
+		return &WriteError{Message: "не удалось отправить запись в journald", Cause: err}
+	}
+	return err
+}
+
+// send отправляет датаграмму; большие записи передаются дескриптором файла.
+// Ошибки потери соединения возвращаются как есть, чтобы Write мог переподключиться
+func (s *JournaldSink) send(data []byte) error {
+	_, err := s.conn.Write(data)
+	if err == nil || journaldDisconnected(err) {
+		return err
+	}
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return &WriteError{Message: "не удалось отправить запись в journald", Cause: err}
+	}
+
+	// Большие записи передаются дескриптором файла с содержимым датаграммы
+	if err := s.sendFile(data); err != nil {
+		return &WriteError{Message: "не удалось отправить большую запись в journald", Cause: err}
+	}
+	return nil
+}
+
+// journaldDisconnected сообщает, что сокет journald пропал или пересоздан
+func journaldDisconnected(err error) bool {
+	return err != nil && (errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOENT) ||
+		errors.Is(err, syscall.ENOTCONN) || errors.Is(err, net.ErrClosed))
+}
+
+// sendFile записывает данные во временный файл и передает его дескриптор journald
+func (s *JournaldSink) sendFile(data []byte) error {
+	f, err := journaldTempFile()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	// journald принимает memfd только запечатанным; для обычного файла вызов завершится ошибкой
+	_, _, _ = syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), fcntlAddSeals, sealAll)
+
+	// WriteMsgUnix запрещен для подключенных датаграммных сокетов, поэтому sendmsg вызывается напрямую
+	raw, err := s.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(f.Fd()))
+	var sendErr error
+	if err := raw.Write(func(fd uintptr) bool {
+		sendErr = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return sendErr != syscall.EAGAIN
+	}); err != nil {
+		return err
+	}
+	return sendErr
+}
+
+// journaldTempFile создает memfd, а если он недоступен, удаленный файл в /dev/shm
+func journaldTempFile() (*os.File, error) {
+	if memfdCreateTrap != 0 {
+		name, err := syscall.BytePtrFromString(journaldMemfdTag)
+		if err != nil {
+			return nil, err
+		}
+		fd, _, errno := syscall.Syscall(memfdCreateTrap, uintptr(unsafe.Pointer(name)), mfdCloexec|mfdAllowSealing, 0)
+		if errno == 0 {
+			return os.NewFile(fd, journaldMemfdTag), nil
+		}
+	}
+
+	f, err := os.CreateTemp(journaldShmDir, journaldMemfdTag)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(f.Name()); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Close реализует интерфейс Sink
+func (s *JournaldSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
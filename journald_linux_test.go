@@ -0,0 +1,107 @@
+//go:build linux
+
+package logger
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listenJournald создает локальный датаграммный сокет вместо journald
+func listenJournald(t *testing.T) (*net.UnixConn, string) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	return conn, path
+}
+
+func TestJournaldSinkDatagram(t *testing.T) {
+	conn, path := listenJournald(t)
+	sink, err := NewJournaldSink(JournaldConfig{Path: path})
+	require.NoError(t, err)
+
+	l := New().WithSink(sink).WithPrefix("API").WithFields(map[string]interface{}{"user": "bob"})
+	l.Warning("slow request")
+	require.NoError(t, l.Close())
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "MESSAGE=slow request\nPRIORITY=4\nSYSLOG_IDENTIFIER=API\nUSER=bob\n", string(buf[:n]))
+
+	assert.Error(t, sink.Write(&Record{Message: "late"}))
+}
+
+func TestJournaldSinkLargeRecordUsesFile(t *testing.T) {
+	conn, path := listenJournald(t)
+	sink, err := NewJournaldSink(JournaldConfig{Path: path, Identifier: "svc"})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	msg := strings.Repeat("x", 4<<20)
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: msg}))
+
+	buf := make([]byte, 16)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	require.NoError(t, err)
+	require.Len(t, fds, 1)
+
+	f := os.NewFile(uintptr(fds[0]), "journal")
+	defer f.Close()
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "MESSAGE="+msg+"\nPRIORITY=6\nSYSLOG_IDENTIFIER=svc\n", string(data))
+}
+
+func TestJournaldSinkMissingSocket(t *testing.T) {
+	_, err := NewJournaldSink(JournaldConfig{Path: filepath.Join(t.TempDir(), "none.sock")})
+	var writeErr *WriteError
+	assert.ErrorAs(t, err, &writeErr)
+}
+
+func TestJournaldSinkReconnectsAfterRestart(t *testing.T) {
+	conn, path := listenJournald(t)
+	sink, err := NewJournaldSink(JournaldConfig{Path: path, Identifier: "svc"})
+	require.NoError(t, err)
+	defer sink.Close()
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: "before"}))
+
+	// journald остановлен: сокет закрыт и удален
+	require.NoError(t, conn.Close())
+	require.NoError(t, os.Remove(path))
+	assert.Error(t, sink.Write(&Record{Level: InfoLevel, Message: "down"}))
+	assert.Error(t, sink.Write(&Record{Level: InfoLevel, Message: "still down"}))
+
+	// После перезапуска сокет создается заново по тому же пути
+	restarted, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer restarted.Close()
+	require.NoError(t, restarted.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: "after"}))
+	buf := make([]byte, 4096)
+	n, err := restarted.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "MESSAGE=after\nPRIORITY=6\nSYSLOG_IDENTIFIER=svc\n", string(buf[:n]))
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package logger
+
+// JournaldSink передает записи в systemd-journald; доступен только в Linux
+type JournaldSink struct{}
+
+// NewJournaldSink возвращает ошибку: journald доступен только в Linux
+func NewJournaldSink(cfg JournaldConfig) (*JournaldSink, error) {
+	return nil, &WriteError{Message: "journald доступен только в Linux"}
+}
+
+// Write реализует интерфейс Sink
+func (s *JournaldSink) Write(r *Record) error {
+	return &WriteError{Message: "journald доступен только в Linux"}
+}
+
+// Close реализует интерфейс Sink
+func (s *JournaldSink) Close() error {
+	return nil
+}
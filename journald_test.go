@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeJournald(t *testing.T) {
+	r := &Record{
+		Level:   ErrorLevel,
+		Prefix:  "PAYMENTS",
+		Message: "charge failed",
+		Caller:  &Caller{File: "app/pay.go", Line: 12, Function: "app.Charge"},
+		Fields: map[string]interface{}{
+			"order-id": 42,
+			"_hidden":  "x",
+			"2fa":      true,
+			"err":      errors.New("declined"),
+		},
+	}
+
+	want := "MESSAGE=charge failed\n" +
+		"PRIORITY=3\n" +
+		"SYSLOG_IDENTIFIER=PAYMENTS\n" +
+		"CODE_FILE=app/pay.go\n" +
+		"CODE_LINE=12\n" +
+		"CODE_FUNC=app.Charge\n" +
+		"F_2FA=true\n" +
+		"HIDDEN=x\n" +
+		"ERR=declined\n" +
+		"ERR_TYPE=*errors.errorString\n" +
+		"ORDER_ID=42\n"
+	assert.Equal(t, want, string(encodeJournald(r, "")))
+}
+
+func TestEncodeJournaldMultiline(t *testing.T) {
+	r := &Record{Level: InfoLevel, Message: "a\nb"}
+
+	data := encodeJournald(r, "svc")
+	prefix := "MESSAGE\n"
+	assert.Equal(t, prefix, string(data[:len(prefix)]))
+	assert.Equal(t, uint64(3), binary.LittleEndian.Uint64(data[len(prefix):len(prefix)+8]))
+	assert.Equal(t, "a\nb\nPRIORITY=6\nSYSLOG_IDENTIFIER=svc\n", string(data[len(prefix)+8:]))
+}
+
+func TestJournaldFieldName(t *testing.T) {
+	assert.Equal(t, "USER_ID", journaldFieldName("user.id"))
+	assert.Equal(t, "F_1ST", journaldFieldName("1st"))
+	assert.Equal(t, "", journaldFieldName("__"))
+	assert.Len(t, journaldFieldName(string(make([]byte, 100))+"a"), 1)
+}
@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Lazy значение поля или аргумента, вычисляемое только при фактической записи.
+// Функция вызывается после проверки уровня и сэмплирования, поэтому дорогие
+// вычисления не выполняются для отброшенных записей:
+//
+//	log.Debugf("state: %v", logger.Lazy(func() interface{} { return dumpState() }))
+//	log.WithFields(logger.Fields{"state": logger.Lazy(func() interface{} { return dumpState() })}).Debug("tick")
+type Lazy func() interface{}
+
+// Format реализует fmt.Formatter: значение вычисляется при форматировании
+// и выводится с исходными флагами, шириной и точностью
+func (f Lazy) Format(s fmt.State, verb rune) {
+	format := "%"
+	for _, flag := range "+-# 0" {
+		if s.Flag(int(flag)) {
+			format += string(flag)
+		}
+	}
+	if width, ok := s.Width(); ok {
+		format += strconv.Itoa(width)
+	}
+	if prec, ok := s.Precision(); ok {
+		format += "." + strconv.Itoa(prec)
+	}
+	fmt.Fprintf(s, format+string(verb), f.value())
+}
+
+// value вычисляет значение; nil-функция дает nil
+func (f Lazy) value() interface{} {
+	if f == nil {
+		return nil
+	}
+	return f()
+}
+
+// lazyPlaceholder заменяет ленивые аргументы в шаблоне сэмплирования
+const lazyPlaceholder = "<lazy>"
+
+// lazyTemplate возвращает аргументы, в которых ленивые значения заменены заполнителем,
+// и признак их наличия. Без ленивых значений возвращаются исходные аргументы
+func lazyTemplate(args []interface{}) ([]interface{}, bool) {
+	var template []interface{}
+	for i, arg := range args {
+		if _, ok := arg.(Lazy); !ok {
+			continue
+		}
+		if template == nil {
+			template = append([]interface{}{}, args...)
+		}
+		template[i] = lazyPlaceholder
+	}
+	if template == nil {
+		return args, false
+	}
+	return template, true
+}
+
+// resolveLazy возвращает поля с вычисленными ленивыми значениями.
+// Если ленивых значений нет, возвращается исходная map без копирования
+func resolveLazy(fields map[string]interface{}) map[string]interface{} {
+	var resolved map[string]interface{}
+	for k, v := range fields {
+		lazy, ok := v.(Lazy)
+		if !ok {
+			continue
+		}
+		if resolved == nil {
+			resolved = copyFields(fields)
+		}
+		resolved[k] = lazy.value()
+	}
+	if resolved == nil {
+		return fields
+	}
+	return resolved
+}
+
+// Enabled сообщает, будут ли записываться сообщения указанного уровня.
+// Позволяет пропустить подготовку данных для отключенных уровней
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// DebugEnabled сообщает, включен ли уровень DEBUG
+func (l *Logger) DebugEnabled() bool {
+	return l.Enabled(DebugLevel)
+}
@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazyNotEvaluatedWhenDisabled(t *testing.T) {
+	calls := 0
+	expensive := Lazy(func() interface{} {
+		calls++
+		return "dump"
+	})
+
+	l := New().WithLevel("info")
+	l.Debugf("state: %v", expensive)
+	l.Debug("state: ", expensive)
+	l.WithFields(map[string]interface{}{"state": expensive}).Debug("tick")
+	assert.Equal(t, 0, calls)
+
+	l.Infof("state: %v", expensive)
+	child := l.WithFields(map[string]interface{}{"state": expensive})
+	child.Info("tick")
+	assert.Equal(t, 2, calls)
+
+	assert.Contains(t, l.GetMessages()[0], "[INFO] state: dump")
+	messages := child.GetMessages()
+	assert.Contains(t, messages[len(messages)-1], "[INFO] tick [state=dump]")
+}
+
+func TestLazyNotEvaluatedWhenSampledOut(t *testing.T) {
+	calls := 0
+	expensive := Lazy(func() interface{} {
+		calls++
+		return calls
+	})
+
+	s, _ := newTestSampler(SamplingConfig{Interval: time.Minute, Initial: 2})
+	l := New().WithSampler(s).WithFields(map[string]interface{}{"n": expensive})
+	for i := 0; i < 10; i++ {
+		l.Debugf("value %v", expensive)
+	}
+	// Для каждой из двух пропущенных записей вычисляются аргумент и поле
+	assert.Equal(t, 4, calls)
+}
+
+func TestLazyFormatFlags(t *testing.T) {
+	pi := Lazy(func() interface{} { return 3.14159 })
+	assert.Equal(t, "  3.14", fmt.Sprintf("%6.2f", pi))
+	assert.Equal(t, "+5", fmt.Sprintf("%+d", Lazy(func() interface{} { return 5 })))
+	assert.Equal(t, "<nil>", fmt.Sprint(Lazy(nil)))
+}
+
+func TestEnabled(t *testing.T) {
+	l := New().WithLevel("warning")
+	assert.False(t, l.DebugEnabled())
+	assert.False(t, l.Enabled(InfoLevel))
+	assert.True(t, l.Enabled(WarningLevel))
+	assert.True(t, l.Enabled(FatalLevel))
+
+	mock := NewMockLogger()
+	require.NoError(t, mock.SetLevel("error"))
+	child := mock.WithPrefix("CHILD")
+	assert.False(t, child.DebugEnabled())
+	assert.True(t, child.Enabled(ErrorLevel))
+}
+
+func TestLazyArgumentNotEvaluatedWhenSampledOut(t *testing.T) {
+	calls := 0
+	expensive := Lazy(func() interface{} {
+		calls++
+		return calls
+	})
+
+	s, _ := newTestSampler(SamplingConfig{Interval: time.Minute, Initial: 1})
+	l := New().WithSampler(s)
+	for i := 0; i < 5; i++ {
+		l.Info("value ", expensive)
+	}
+	assert.Equal(t, 1, calls)
+
+	messages := l.GetMessages()
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "[INFO] value 1")
+}
+
+func TestLazyFieldNotEvaluatedWhenFiltered(t *testing.T) {
+	calls := 0
+	expensive := Lazy(func() interface{} {
+		calls++
+		return "dump"
+	})
+
+	f, err := NewFilter([]FilterRule{{Action: "exclude", Prefix: "NOISY"}})
+	require.NoError(t, err)
+	l := New().WithFilter(f).WithPrefix("NOISY").WithFields(map[string]interface{}{"state": expensive})
+	l.Info("tick")
+	assert.Equal(t, 0, calls)
+}
@@ -8,7 +8,6 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
-	"time"
 )
 
 // Для возможности тестирования
@@ -60,21 +59,48 @@ func (l *Logger) SetLevel(level string) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.level = parsed
+	return nil
+}
+
+// ParseLevel преобразует строковое название уровня логирования в Level
+func ParseLevel(level string) (Level, error) {
 	switch strings.ToLower(level) {
 	case "debug":
-		l.level = DebugLevel
+		return DebugLevel, nil
 	case "info":
-		l.level = InfoLevel
+		return InfoLevel, nil
 	case "warning":
-		l.level = WarningLevel
+		return WarningLevel, nil
 	case "error":
-		l.level = ErrorLevel
+		return ErrorLevel, nil
 	case "fatal":
-		l.level = FatalLevel
+		return FatalLevel, nil
 	default:
-		return fmt.Errorf("неизвестный уровень логирования: %s", level)
+		return DebugLevel, &LevelError{Level: level}
+	}
+}
+
+// String возвращает строковое представление уровня логирования
+func (lv Level) String() string {
+	switch lv {
+	case DebugLevel:
+		return DebugStr
+	case InfoLevel:
+		return InfoStr
+	case WarningLevel:
+		return "WARNING"
+	case ErrorLevel:
+		return ErrorStr
+	case FatalLevel:
+		return FatalStr
+	default:
+		return "UNKNOWN"
 	}
-	return nil
 }
 
 // WithFile создает новый логгер с записью в файл
@@ -92,35 +118,100 @@ func (l *Logger) WithFile(filename string) ILogger {
 		return l
 	}
 
-	newLogger := &Logger{
-		prefix:    l.prefix,
-		fields:    make(map[string]interface{}),
-		file:      f,
-		errorFile: l.errorFile,
-		level:     l.level,
-		mu:        &sync.RWMutex{},
-		logger:    log.New(io.MultiWriter(os.Stdout, f), "", 0),
-	}
+	newLogger := l.clone()
+	newLogger.file = f
+	newLogger.mu = &sync.RWMutex{}
+	newLogger.logger = log.New(io.MultiWriter(os.Stdout, f), "", 0)
+	newLogger.messages = []string{}
 
-	// Копируем поля
-	for k, v := range l.fields {
-		newLogger.fields[k] = v
+	return newLogger
+}
+
+func (l *Logger) log(level Level, args ...interface{}) {
+	// Аргументы форматируются только после проверки уровня
+	if level < l.level {
+		return
+	}
+	// Сэмплирование выполняется по шаблону, в котором ленивые аргументы заменены
+	// заполнителем, поэтому они вычисляются только для пропущенных записей
+	template, lazy := lazyTemplate(args)
+	msg := fmt.Sprint(template...)
+	if !l.sample(level, msg) {
+		l.countDropped(l.prefix, DropSampled)
+		return
 	}
+	if lazy {
+		msg = fmt.Sprint(args...)
+	}
+	l.output(level, msg, l.fields)
+}
 
-	return newLogger
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	// Сэмплирование по шаблону выполняется до форматирования, чтобы не тратить на него время
+	if level < l.level {
+		return
+	}
+	if !l.sample(level, format) {
+		l.countDropped(l.prefix, DropSampled)
+		return
+	}
+	l.output(level, fmt.Sprintf(format, args...), l.fields)
 }
 
-func (l *Logger) log(level Level, msg string) {
+// output формирует запись с указанными полями и записывает ее во все назначения.
+// Вызывается только из log, logf и logw, чтобы глубина стека до кода пользователя была постоянной
+func (l *Logger) output(level Level, msg string, fields map[string]interface{}) {
 	if level < l.level {
 		return
 	}
 
-	if l.prefix != "" {
-		msg = "[" + l.prefix + "] " + msg
+	record := l.newRecord(level, msg, fields)
+	if !l.allowed(record) {
+		l.countDropped(record.Prefix, DropFiltered)
+		return
+	}
+	record.Fields = resolveLazy(record.Fields)
+	if l.addCaller {
+		record.Caller = captureCaller(callerDepth + l.callerSkip)
+	}
+	if l.addStacktrace && level >= l.stacktraceLevel {
+		// Трассировка из ошибки указывает на место ее возникновения и полезнее места логирования
+		record.Stack = errorStack(record.Fields)
+		if record.Stack == "" {
+			record.Stack = captureStack(callerDepth + l.callerSkip)
+		}
+	}
+	if l.redactor != nil {
+		l.redactor.Redact(record)
+	}
+	if l.dedup != nil && !l.dedup.admit(l, record) {
+		l.countDropped(record.Prefix, DropDeduplicated)
+		return
 	}
 
-	formattedMsg := l.formatMessage(level, msg)
+	formattedMsg := l.encode(record)
+	if !l.rateLimit(record, len(formattedMsg)+1) {
+		l.countDropped(record.Prefix, DropRateLimited)
+		return
+	}
+	l.countRecord(record, len(formattedMsg)+1)
+	l.fireHooks(record)
+	l.writeEncoded(record.Level, formattedMsg)
+	l.writeSinks(record)
+}
 
+// write кодирует готовую запись и выводит ее в консоль и файлы.
+// Используется для служебных записей, минуя сэмплирование и ограничения
+func (l *Logger) write(record *Record) {
+	formattedMsg := l.encode(record)
+	l.countRecord(record, len(formattedMsg)+1)
+	l.fireHooks(record)
+	l.writeEncoded(record.Level, formattedMsg)
+	l.writeSinks(record)
+}
+
+// writeEncoded выводит закодированную запись в консоль и файлы
+func (l *Logger) writeEncoded(level Level, formattedMsg string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -128,7 +219,7 @@ func (l *Logger) log(level Level, msg string) {
 	l.messages = append(l.messages, formattedMsg)
 	l.messagesMu.Unlock()
 
-	if _, err := fmt.Fprintln(os.Stdout, formattedMsg); err != nil {
+	if _, err := fmt.Fprintln(l.console(level), formattedMsg); err != nil {
 		l.handleWriteError(err)
 	}
 
@@ -151,18 +242,9 @@ func (l *Logger) log(level Level, msg string) {
 	}
 }
 
-func (l *Logger) logf(level Level, format string, args ...interface{}) {
-	if level < l.level {
-		return
-	}
-	msg := fmt.Sprintf(format, args...)
-	l.log(level, msg)
-}
-
 // Debug логирует сообщение на уровне DEBUG
 func (l *Logger) Debug(args ...interface{}) {
-	msg := fmt.Sprint(args...)
-	l.log(DebugLevel, msg)
+	l.log(DebugLevel, args...)
 }
 
 // Debugf логирует форматированное сообщение на уровне DEBUG
@@ -172,8 +254,7 @@ func (l *Logger) Debugf(format string, args ...interface{}) {
 
 // Info логирует сообщение на уровне INFO
 func (l *Logger) Info(args ...interface{}) {
-	msg := fmt.Sprint(args...)
-	l.log(InfoLevel, msg)
+	l.log(InfoLevel, args...)
 }
 
 // Infof логирует форматированное сообщение на уровне INFO
@@ -183,8 +264,7 @@ func (l *Logger) Infof(format string, args ...interface{}) {
 
 // Warning логирует сообщение на уровне WARNING
 func (l *Logger) Warning(args ...interface{}) {
-	msg := fmt.Sprint(args...)
-	l.log(WarningLevel, msg)
+	l.log(WarningLevel, args...)
 }
 
 // Warningf логирует форматированное сообщение на уровне WARNING
@@ -194,8 +274,7 @@ func (l *Logger) Warningf(format string, args ...interface{}) {
 
 // Error логирует сообщение на уровне ERROR
 func (l *Logger) Error(args ...interface{}) {
-	msg := fmt.Sprint(args...)
-	l.log(ErrorLevel, msg)
+	l.log(ErrorLevel, args...)
 }
 
 // Errorf логирует форматированное сообщение на уровне ERROR
@@ -205,18 +284,20 @@ func (l *Logger) Errorf(format string, args ...interface{}) {
 
 // Fatal логирует фатальную ошибку и завершает программу
 func (l *Logger) Fatal(args ...interface{}) {
-	msg := fmt.Sprint(args...)
-	l.log(FatalLevel, msg)
-	if err := l.Close(); err != nil {
-		l.Error("failed to close logger:", err)
-	}
-	osExit(1)
+	l.log(FatalLevel, args...)
+	l.exit()
 }
 
 // Fatalf логирует фатальную ошибку с форматированием и завершает программу
 func (l *Logger) Fatalf(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	l.log(FatalLevel, msg)
+	l.logf(FatalLevel, format, args...)
+	l.exit()
+}
+
+// exit закрывает логгер и завершает программу после записи фатальной ошибки
+func (l *Logger) exit() {
+	// Очереди всех хуков, включая унаследованные от родителя, обрабатываются до завершения
+	l.closeHooks(true)
 	if err := l.Close(); err != nil {
 		l.Error("failed to close logger:", err)
 	}
@@ -252,11 +333,18 @@ func (l *Logger) WithFields(fields map[string]interface{}) ILogger {
 
 // Close закрывает файлы логов, если они открыты
 func (l *Logger) Close() error {
+	// Сводки сэмплера, итоги серий повторов и отчеты ограничителя выводятся до закрытия файлов
+	l.flushSampler()
+	if l.dedup != nil {
+		l.dedup.flush()
+	}
+	l.flushRateLimiter()
+	l.closeHooks(false)
+	closeErr := l.closeSinks()
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	var closeErr error
-
 	if l.file != nil {
 		if syncErr := l.file.Sync(); syncErr != nil {
 			l.handleWriteError(syncErr)
@@ -294,46 +382,41 @@ func (l *Logger) clone() *Logger {
 		timeFormat: l.timeFormat,
 		messages:   append([]string{}, l.messages...),
 		messagesMu: sync.RWMutex{},
+		encoder:    l.encoder,
+		callerSkip: l.callerSkip,
+		addCaller:  l.addCaller,
+
+		stacktraceLevel: l.stacktraceLevel,
+		addStacktrace:   l.addStacktrace,
+		stdout:          l.stdout,
+		stderr:          l.stderr,
+		stderrLevel:     l.stderrLevel,
+		splitStderr:     l.splitStderr,
+		redactor:        l.redactor,
+		control:         l.control,
+		sampler:         l.sampler,
+		dedup:           l.dedup,
+		limiter:         l.limiter,
+		hooks:           l.hooks,
+		filters:         l.filters,
+		sinks:           l.sinks,
+		metrics:         l.metrics,
 	}
 }
 
-func (l *Logger) formatMessage(level Level, msg string) string {
-	timeFormat := "2006-01-02 15:04:05"
-	if l.timeFormat != "" {
-		timeFormat = l.timeFormat
-	}
-	timestamp := time.Now().Format(timeFormat)
-
-	// Получаем строковое представление уровня
-	levelStr := ""
-	switch level {
-	case DebugLevel:
-		levelStr = "[DEBUG]"
-	case InfoLevel:
-		levelStr = "[INFO]"
-	case WarningLevel:
-		levelStr = "[WARNING]"
-	case ErrorLevel:
-		levelStr = "[ERROR]"
-	case FatalLevel:
-		levelStr = "[FATAL]"
-	default:
-		levelStr = "[UNKNOWN]"
-	}
-
-	// Формируем строку с полями
-	if len(l.fields) > 0 {
-		var fields []string
-		for k, v := range l.fields {
-			fields = append(fields, fmt.Sprintf("%s=%v", k, v))
+// encode кодирует запись выбранным кодировщиком, по умолчанию текстовым
+func (l *Logger) encode(r *Record) string {
+	switch e := l.encoder.(type) {
+	case nil:
+		return TextEncoder{TimeFormat: l.timeFormat, Control: l.control}.Encode(r)
+	case JSONEncoder:
+		// Формат времени логгера читается при каждой записи, чтобы учитывать WithTimeFormat
+		if e.TimeFormat == "" {
+			e.TimeFormat = l.timeFormat
 		}
-		msg += " [" + strings.Join(fields, " ") + "]"
+		return e.Encode(r)
 	}
-
-	// Формируем части сообщения
-	parts := []string{timestamp, levelStr, msg}
-
-	return strings.Join(parts, " ")
+	return l.encoder.Encode(r)
 }
 
 // WithLevel создает новый логгер с указанным уровнем логирования
@@ -355,10 +438,91 @@ func (l *Logger) WithConfig(cfg *Config) ILogger {
 			newLogger.Error("failed to set level:", err)
 		}
 	}
+	newLogger.applyOutputConfig(cfg)
+	newLogger.applyPipelineConfig(cfg)
+	newLogger.applyFileSinks(cfg, l.timeFormat)
 	// Применяем другие настройки конфигурации...
 	return newLogger
 }
 
+// applyOutputConfig применяет параметры представления записей и вывода в stderr
+func (l *Logger) applyOutputConfig(cfg *Config) {
+	switch strings.ToLower(cfg.Format) {
+	case FormatJSON:
+		l.encoder = JSONEncoder{}
+	case FormatText:
+		l.encoder = nil
+	}
+	if cfg.Caller {
+		l.addCaller = true
+	}
+	if cfg.ControlChars != "" {
+		if policy, err := ParseControlPolicy(cfg.ControlChars); err == nil {
+			l.control = policy
+		}
+	}
+	if cfg.StacktraceLevel != "" {
+		if level, err := ParseLevel(cfg.StacktraceLevel); err == nil {
+			l.stacktraceLevel = level
+			l.addStacktrace = true
+		}
+	}
+	if cfg.SplitStderr {
+		l.splitStderr = true
+		l.stderrLevel = DefaultStderrLevel
+		if cfg.StderrLevel != "" {
+			if level, err := ParseLevel(cfg.StderrLevel); err == nil {
+				l.stderrLevel = level
+			}
+		}
+	}
+}
+
+// applyPipelineConfig применяет маскирование, сэмплирование, подавление повторов,
+// ограничение потока, метрики и фильтры
+func (l *Logger) applyPipelineConfig(cfg *Config) {
+	if cfg.Redact != nil {
+		redactor, err := NewRedactor(*cfg.Redact)
+		if err != nil {
+			l.Error("failed to configure redaction:", err)
+		} else {
+			l.redactor = redactor
+		}
+	}
+	if cfg.Sampling != nil {
+		l.sampler = NewSampler(*cfg.Sampling)
+	}
+	if cfg.DedupWindow > 0 {
+		l.dedup = NewDeduplicator(cfg.DedupWindow)
+	}
+	if cfg.RateLimit != nil {
+		l.limiter = NewRateLimiter(*cfg.RateLimit)
+	}
+	if cfg.Metrics && l.metrics == nil {
+		l.metrics = NewMetrics()
+	}
+	if len(cfg.Filters) > 0 {
+		filter, err := NewFilter(cfg.Filters)
+		if err != nil {
+			l.Error("failed to configure filters:", err)
+		} else {
+			l.filters = append(append([]Filter{}, l.filters...), filter)
+		}
+	}
+}
+
+// applyFileSinks добавляет дополнительные файлы из конфигурации
+func (l *Logger) applyFileSinks(cfg *Config, timeFormat string) {
+	for _, sc := range cfg.FileSinks {
+		sink, err := newFileSinkFromConfig(sc, timeFormat, l.control)
+		if err != nil {
+			l.Error("failed to configure file sink:", err)
+			continue
+		}
+		l.sinks = append(append([]Sink{}, l.sinks...), sink)
+	}
+}
+
 // WithTimeFormat устанавливает формат времени для логгера
 func (l *Logger) WithTimeFormat(format string) ILogger {
 	newLogger := l.clone()
@@ -376,6 +540,9 @@ func (l *Logger) GetMessages() []string {
 // Добавляем метод обработки ошибок записи
 func (l *Logger) handleWriteError(err error) {
 	// Логирование ошибки или другая обработка
+	if l.metrics != nil {
+		l.metrics.writeError()
+	}
 }
 
 // Sync принудительно синхронизирует буферы файла логов
@@ -0,0 +1,235 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LokiLevelLabel и LokiPrefixLabel метки потока с уровнем и префиксом записи
+const (
+	LokiLevelLabel  = "level"
+	LokiPrefixLabel = "prefix"
+)
+
+// LokiConfig параметры назначения Grafana Loki
+type LokiConfig struct {
+	BatchConfig
+	RetryConfig
+	// Client HTTP-клиент, по умолчанию клиент с таймаутом DefaultHTTPTimeout
+	Client *http.Client
+	// Headers дополнительные заголовки запросов, например Authorization
+	Headers map[string]string
+	// Labels постоянные метки всех потоков, например job или env
+	Labels map[string]string
+	// Encoder кодировщик строки записи, по умолчанию JSONEncoder
+	Encoder Encoder
+	// URL адрес push API, например http://loki:3100/loki/api/v1/push
+	URL string
+	// TenantID идентификатор арендатора, передаваемый в заголовке X-Scope-OrgID
+	TenantID string
+	// LabelFields поля записи, которые становятся метками потока и не попадают в строку.
+	// Следует выбирать поля с небольшим числом значений. Имена встроенных меток
+	// LokiLevelLabel и LokiPrefixLabel недопустимы
+	LabelFields []string
+	// Protobuf включает формат protobuf со сжатием snappy вместо JSON
+	Protobuf bool
+}
+
+// LokiSink отправляет записи в Loki через push API. Уровень, префикс и поля
+// из LabelFields образуют метки потока, остальные поля остаются в строке
+type LokiSink struct {
+	*batchSender
+	cfg LokiConfig
+}
+
+// lokiStream записи одного потока
+type lokiStream struct {
+	labels  map[string]string
+	key     string
+	entries []batchEntry
+}
+
+// NewLokiSink создает назначение Loki
+func NewLokiSink(cfg LokiConfig) (*LokiSink, error) {
+	if cfg.URL == "" {
+		return nil, &ConfigError{Err: fmt.Errorf("не указан URL"), Reason: "некорректное назначение Loki"}
+	}
+	for _, k := range cfg.LabelFields {
+		if name := lokiLabelName(k); name == LokiLevelLabel || name == LokiPrefixLabel {
+			return nil, &ConfigError{Err: fmt.Errorf("поле %q совпадает со встроенной меткой %s", k, name), Reason: "некорректное назначение Loki"}
+		}
+	}
+	if cfg.Encoder == nil {
+		cfg.Encoder = JSONEncoder{}
+	}
+
+	s := &LokiSink{cfg: cfg}
+	s.batchSender = newBatchSender("loki", cfg.BatchConfig, cfg.Client, cfg.RetryConfig, s.request)
+	return s, nil
+}
+
+// Write реализует интерфейс Sink. Поля-метки исключаются из строки записи
+func (s *LokiSink) Write(r *Record) error {
+	line := r
+	if len(s.cfg.LabelFields) > 0 {
+		copied := *r
+		copied.Fields = copyFields(r.Fields)
+		for _, k := range s.cfg.LabelFields {
+			delete(copied.Fields, k)
+		}
+		line = &copied
+	}
+	return s.add(batchEntry{record: r, data: []byte(s.cfg.Encoder.Encode(line))})
+}
+
+// labels возвращает метки потока записи
+func (s *LokiSink) labels(r *Record) map[string]string {
+	labels := make(map[string]string, len(s.cfg.Labels)+len(s.cfg.LabelFields)+2)
+	for k, v := range s.cfg.Labels {
+		labels[lokiLabelName(k)] = v
+	}
+	labels[LokiLevelLabel] = strings.ToLower(r.Level.String())
+	if r.Prefix != "" {
+		labels[LokiPrefixLabel] = r.Prefix
+	}
+	for _, k := range s.cfg.LabelFields {
+		if v, ok := r.Fields[k]; ok {
+			labels[lokiLabelName(k)] = fmt.Sprint(v)
+		}
+	}
+	return labels
+}
+
+// streams группирует записи пакета по наборам меток в порядке появления
+func (s *LokiSink) streams(batch []batchEntry) []*lokiStream {
+	var streams []*lokiStream
+	byKey := make(map[string]*lokiStream)
+	for _, e := range batch {
+		labels := s.labels(e.record)
+		key := lokiLabelString(labels)
+		stream, ok := byKey[key]
+		if !ok {
+			stream = &lokiStream{labels: labels, key: key}
+			byKey[key] = stream
+			streams = append(streams, stream)
+		}
+		stream.entries = append(stream.entries, e)
+	}
+	// Loki ожидает записи потока в порядке времени
+	for _, stream := range streams {
+		entries := stream.entries
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].record.Time.Before(entries[j].record.Time)
+		})
+	}
+	return streams
+}
+
+// request строит push-запрос в формате JSON или protobuf
+func (s *LokiSink) request(batch []batchEntry) (func() (*http.Request, error), error) {
+	headers := make(map[string]string, len(s.cfg.Headers)+1)
+	for k, v := range s.cfg.Headers {
+		headers[k] = v
+	}
+	if s.cfg.TenantID != "" {
+		headers["X-Scope-OrgID"] = s.cfg.TenantID
+	}
+
+	streams := s.streams(batch)
+	if s.cfg.Protobuf {
+		body := snappyEncode(encodeLokiProtobuf(streams))
+		return newBodyRequest(http.MethodPost, s.cfg.URL, "application/x-protobuf", headers, body, false)
+	}
+	return newBodyRequest(http.MethodPost, s.cfg.URL, "application/json", headers, encodeLokiJSON(streams), false)
+}
+
+// encodeLokiJSON кодирует потоки в JSON-формат push API:
+// {"streams":[{"stream":{"label":"value"},"values":[["<unix ns>","line"]]}]}
+func encodeLokiJSON(streams []*lokiStream) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"streams":[`)
+	for i, stream := range streams {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(`{"stream":`)
+		b.Write(marshalJSON(stream.labels))
+		b.WriteString(`,"values":[`)
+		for j, e := range stream.entries {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(`["` + strconv.FormatInt(e.record.Time.UnixNano(), 10) + `",`)
+			b.Write(marshalJSON(string(e.data)))
+			b.WriteByte(']')
+		}
+		b.WriteString("]}")
+	}
+	b.WriteString("]}")
+	return b.Bytes()
+}
+
+// encodeLokiProtobuf кодирует потоки в сообщение logproto.PushRequest:
+//
+//	PushRequest   { repeated StreamAdapter streams = 1; }
+//	StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	EntryAdapter  { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+//	Timestamp     { int64 seconds = 1; int32 nanos = 2; }
+func encodeLokiProtobuf(streams []*lokiStream) []byte {
+	var p protoBuffer
+	for _, stream := range streams {
+		stream := stream
+		p.message(1, func(m *protoBuffer) {
+			m.stringField(1, stream.key)
+			for _, e := range stream.entries {
+				e := e
+				m.message(2, func(entry *protoBuffer) {
+					entry.message(1, func(ts *protoBuffer) {
+						ts.int64Field(1, e.record.Time.Unix())
+						ts.int64Field(2, int64(e.record.Time.Nanosecond()))
+					})
+					entry.stringField(2, string(e.data))
+				})
+			}
+		})
+	}
+	return p.b
+}
+
+// lokiLabelString формирует строку меток в синтаксисе LogQL: {a="1", b="2"}
+func lokiLabelString(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(k + "=" + strconv.Quote(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// lokiLabelName приводит имя к допустимому имени метки: [a-zA-Z_][a-zA-Z0-9_]*
+func lokiLabelName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= '0' && c <= '9' && i > 0) {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 {
+		return "_"
+	}
+	return string(b)
+}
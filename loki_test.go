@@ -0,0 +1,241 @@
+package logger
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lokiPush принятый фейковым сервером запрос
+type lokiPush struct {
+	header http.Header
+	body   []byte
+}
+
+// newLokiServer запускает фейковый push API
+func newLokiServer(t *testing.T) (*httptest.Server, func() []lokiPush) {
+	var (
+		mu     sync.Mutex
+		pushes []lokiPush
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		pushes = append(pushes, lokiPush{header: r.Header.Clone(), body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []lokiPush {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]lokiPush{}, pushes...)
+	}
+}
+
+func TestLokiSinkJSON(t *testing.T) {
+	srv, pushes := newLokiServer(t)
+	sink, err := NewLokiSink(LokiConfig{
+		URL:         srv.URL + "/loki/api/v1/push",
+		TenantID:    "team-a",
+		Labels:      map[string]string{"job": "billing"},
+		LabelFields: []string{"region"},
+	})
+	require.NoError(t, err)
+
+	l := New().WithSink(sink).WithPrefix("PAYMENTS").WithFields(map[string]interface{}{"region": "eu", "order": 7})
+	l.Info("charged")
+	l.Error("declined")
+	require.NoError(t, l.Close())
+
+	require.Len(t, pushes(), 1)
+	push := pushes()[0]
+	assert.Equal(t, "team-a", push.header.Get("X-Scope-OrgID"))
+	assert.Equal(t, "application/json", push.header.Get("Content-Type"))
+
+	var req struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	require.NoError(t, json.Unmarshal(push.body, &req))
+	require.Len(t, req.Streams, 2)
+	assert.Equal(t, map[string]string{"job": "billing", "level": "info", "prefix": "PAYMENTS", "region": "eu"}, req.Streams[0].Stream)
+	assert.Equal(t, "error", req.Streams[1].Stream["level"])
+
+	line := req.Streams[0].Values[0][1]
+	assert.Contains(t, line, `"order":7`)
+	assert.NotContains(t, line, "region")
+	assert.Regexp(t, `^\d{19}$`, req.Streams[0].Values[0][0])
+	assert.Equal(t, uint64(2), sink.Stats().SentRecords)
+}
+
+func TestLokiSinkProtobuf(t *testing.T) {
+	srv, pushes := newLokiServer(t)
+	sink, err := NewLokiSink(LokiConfig{URL: srv.URL, Encoder: TextEncoder{}, Protobuf: true})
+	require.NoError(t, err)
+
+	ts := time.Unix(1700000000, 123)
+	require.NoError(t, sink.Write(&Record{Time: ts, Level: WarningLevel, Prefix: "API", Message: "slow"}))
+	require.NoError(t, sink.Close())
+
+	require.Len(t, pushes(), 1)
+	assert.Equal(t, "application/x-protobuf", pushes()[0].header.Get("Content-Type"))
+	raw, err := snappyDecode(pushes()[0].body)
+	require.NoError(t, err)
+
+	stream := protoDecode(t, raw)[1][0]
+	fields := protoDecode(t, stream)
+	assert.Equal(t, `{level="warning", prefix="API"}`, string(fields[1][0]))
+	entry := protoDecode(t, fields[2][0])
+	assert.Contains(t, string(entry[2][0]), "[WARNING] [API] slow")
+	timestamp := protoDecode(t, entry[1][0])
+	seconds, _ := binary.Uvarint(timestamp[1][0])
+	nanos, _ := binary.Uvarint(timestamp[2][0])
+	assert.Equal(t, uint64(1700000000), seconds)
+	assert.Equal(t, uint64(123), nanos)
+}
+
+func TestLokiStreamsSortedByTime(t *testing.T) {
+	s := &LokiSink{cfg: LokiConfig{}}
+	now := time.Now()
+	batch := []batchEntry{
+		{record: &Record{Time: now.Add(time.Second), Level: InfoLevel}, data: []byte("b")},
+		{record: &Record{Time: now, Level: InfoLevel}, data: []byte("a")},
+	}
+	streams := s.streams(batch)
+	require.Len(t, streams, 1)
+	assert.Equal(t, "a", string(streams[0].entries[0].data))
+}
+
+func TestLokiLabelName(t *testing.T) {
+	assert.Equal(t, "http_status", lokiLabelName("http.status"))
+	assert.Equal(t, "_st", lokiLabelName("1st"))
+	assert.Equal(t, `{a="x\"y", b="2"}`, lokiLabelString(map[string]string{"b": "2", "a": `x"y`}))
+}
+
+func TestLokiSinkReservedLabelFields(t *testing.T) {
+	for _, field := range []string{"level", "prefix"} {
+		_, err := NewLokiSink(LokiConfig{URL: "http://loki", LabelFields: []string{"service", field}})
+		var cfgErr *ConfigError
+		assert.ErrorAs(t, err, &cfgErr, field)
+	}
+
+	s, err := NewLokiSink(LokiConfig{URL: "http://loki", LabelFields: []string{"service"}})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+}
+
+func TestSnappyRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := make([]byte, 5000)
+	rng.Read(random)
+
+	for _, src := range [][]byte{
+		nil,
+		[]byte("a"),
+		[]byte(strings.Repeat("abcd", 1000)),
+		[]byte(strings.Repeat(`{"level":"info","msg":"request served"}`+"\n", 500)),
+		random,
+		append([]byte(strings.Repeat("x", 70000)), random...),
+	} {
+		encoded := snappyEncode(src)
+		decoded, err := snappyDecode(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, len(src), len(decoded))
+		assert.True(t, string(src) == string(decoded))
+	}
+	assert.Less(t, len(snappyEncode([]byte(strings.Repeat("abcd", 1000)))), 200)
+}
+
+// snappyDecode распаковывает блочный формат snappy
+func snappyDecode(src []byte) ([]byte, error) {
+	size, n := binary.Uvarint(src)
+	if n <= 0 {
+		return nil, errors.New("некорректная длина")
+	}
+	src = src[n:]
+	dst := make([]byte, 0, size)
+	for len(src) > 0 {
+		tag := src[0]
+		switch tag & 3 {
+		case 0:
+			length := int(tag >> 2)
+			src = src[1:]
+			if length >= 60 {
+				extra := length - 59
+				length = 0
+				for i := 0; i < extra; i++ {
+					length |= int(src[i]) << (8 * i)
+				}
+				src = src[extra:]
+			}
+			length++
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+		case 2:
+			length := int(tag>>2) + 1
+			offset := int(src[1]) | int(src[2])<<8
+			src = src[3:]
+			if offset == 0 || offset > len(dst) {
+				return nil, errors.New("некорректное смещение")
+			}
+			for i := 0; i < length; i++ {
+				dst = append(dst, dst[len(dst)-offset])
+			}
+		default:
+			return nil, errors.New("неподдерживаемый элемент")
+		}
+	}
+	if uint64(len(dst)) != size {
+		return nil, errors.New("длина не совпадает")
+	}
+	return dst, nil
+}
+
+// protoDecode разбирает сообщение protobuf в поля: для varint значение
+// возвращается в виде varint, для length-delimited содержимое
+func protoDecode(t *testing.T, data []byte) map[int][][]byte {
+	t.Helper()
+	fields := make(map[int][][]byte)
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		require.Positive(t, n)
+		data = data[n:]
+		field := int(key >> 3)
+		switch key & 7 {
+		case protoVarint:
+			_, n := binary.Uvarint(data)
+			require.Positive(t, n)
+			fields[field] = append(fields[field], data[:n])
+			data = data[n:]
+		case protoFixed64:
+			fields[field] = append(fields[field], data[:8])
+			data = data[8:]
+		case protoFixed32:
+			fields[field] = append(fields[field], data[:4])
+			data = data[4:]
+		case protoBytes:
+			size, n := binary.Uvarint(data)
+			require.Positive(t, n)
+			data = data[n:]
+			fields[field] = append(fields[field], data[:size])
+			data = data[size:]
+		default:
+			t.Fatalf("неподдерживаемый тип поля %d", key&7)
+		}
+	}
+	return fields
+}
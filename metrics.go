@@ -0,0 +1,240 @@
+package logger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Причины отбрасывания записей в метриках
+const (
+	DropSampled      = "sampled"
+	DropFiltered     = "filtered"
+	DropDeduplicated = "deduplicated"
+	DropRateLimited  = "rate_limited"
+)
+
+// MetricsNamespace префикс имен метрик в формате Prometheus
+const MetricsNamespace = "logger"
+
+// levelCounters счетчики записей по уровням
+type levelCounters [FatalLevel + 1]uint64
+
+// dropKey ключ счетчика отброшенных записей
+type dropKey struct {
+	prefix string
+	reason string
+}
+
+// Metrics накапливает счетчики записанных и отброшенных записей. Один экземпляр
+// можно разделять между несколькими логгерами; все методы потокобезопасны
+type Metrics struct {
+	bytes       uint64 // первые поля для выравнивания атомарных счетчиков на 32-битных платформах
+	writeErrors uint64
+	mu          sync.RWMutex
+	records     map[string]*levelCounters
+	dropped     map[dropKey]*uint64
+}
+
+// MetricsSnapshot мгновенный снимок счетчиков
+type MetricsSnapshot struct {
+	// Records количество записей по уровням
+	Records map[Level]uint64
+	// RecordsByPrefix количество записей по префиксам и уровням
+	RecordsByPrefix map[string]map[Level]uint64
+	// Dropped количество отброшенных записей по причинам
+	Dropped map[string]uint64
+	// DroppedByPrefix количество отброшенных записей по префиксам и причинам
+	DroppedByPrefix map[string]map[string]uint64
+	// BytesWritten объем закодированных записей в байтах
+	BytesWritten uint64
+	// WriteErrors количество ошибок записи
+	WriteErrors uint64
+}
+
+// NewMetrics создает пустой набор счетчиков
+func NewMetrics() *Metrics {
+	return &Metrics{
+		records: make(map[string]*levelCounters),
+		dropped: make(map[dropKey]*uint64),
+	}
+}
+
+// record учитывает записанную запись
+func (m *Metrics) record(level Level, prefix string, size int) {
+	if level < DebugLevel || level > FatalLevel {
+		return
+	}
+	m.mu.RLock()
+	c, ok := m.records[prefix]
+	m.mu.RUnlock()
+	if !ok {
+		m.mu.Lock()
+		if c, ok = m.records[prefix]; !ok {
+			c = &levelCounters{}
+			m.records[prefix] = c
+		}
+		m.mu.Unlock()
+	}
+	atomic.AddUint64(&c[level], 1)
+	atomic.AddUint64(&m.bytes, uint64(size))
+}
+
+// drop учитывает отброшенную запись
+func (m *Metrics) drop(prefix, reason string) {
+	key := dropKey{prefix: prefix, reason: reason}
+	m.mu.RLock()
+	c, ok := m.dropped[key]
+	m.mu.RUnlock()
+	if !ok {
+		m.mu.Lock()
+		if c, ok = m.dropped[key]; !ok {
+			c = new(uint64)
+			m.dropped[key] = c
+		}
+		m.mu.Unlock()
+	}
+	atomic.AddUint64(c, 1)
+}
+
+// writeError учитывает ошибку записи
+func (m *Metrics) writeError() {
+	atomic.AddUint64(&m.writeErrors, 1)
+}
+
+// Snapshot возвращает текущие значения счетчиков
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	s := MetricsSnapshot{
+		Records:         make(map[Level]uint64),
+		RecordsByPrefix: make(map[string]map[Level]uint64),
+		Dropped:         make(map[string]uint64),
+		DroppedByPrefix: make(map[string]map[string]uint64),
+		BytesWritten:    atomic.LoadUint64(&m.bytes),
+		WriteErrors:     atomic.LoadUint64(&m.writeErrors),
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for prefix, c := range m.records {
+		byLevel := make(map[Level]uint64)
+		for lv := DebugLevel; lv <= FatalLevel; lv++ {
+			if n := atomic.LoadUint64(&c[lv]); n > 0 {
+				byLevel[lv] = n
+				s.Records[lv] += n
+			}
+		}
+		s.RecordsByPrefix[prefix] = byLevel
+	}
+	for key, c := range m.dropped {
+		n := atomic.LoadUint64(c)
+		s.Dropped[key.reason] += n
+		if s.DroppedByPrefix[key.prefix] == nil {
+			s.DroppedByPrefix[key.prefix] = make(map[string]uint64)
+		}
+		s.DroppedByPrefix[key.prefix][key.reason] = n
+	}
+	return s
+}
+
+// WritePrometheus выводит счетчики в текстовом формате Prometheus
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	s := m.Snapshot()
+	bw := bufio.NewWriter(w)
+
+	writeMetricHeader(bw, "records_total", "Number of log records written by level and prefix.")
+	prefixes := make([]string, 0, len(s.RecordsByPrefix))
+	for prefix := range s.RecordsByPrefix {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		byLevel := s.RecordsByPrefix[prefix]
+		for lv := DebugLevel; lv <= FatalLevel; lv++ {
+			if n, ok := byLevel[lv]; ok {
+				fmt.Fprintf(bw, "%s_records_total{level=%q,prefix=\"%s\"} %d\n",
+					MetricsNamespace, lv.String(), escapeLabel(prefix), n)
+			}
+		}
+	}
+
+	writeMetricHeader(bw, "dropped_records_total", "Number of log records dropped by reason and prefix.")
+	prefixes = prefixes[:0]
+	for prefix := range s.DroppedByPrefix {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		byReason := s.DroppedByPrefix[prefix]
+		reasons := make([]string, 0, len(byReason))
+		for reason := range byReason {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		for _, reason := range reasons {
+			fmt.Fprintf(bw, "%s_dropped_records_total{reason=%q,prefix=\"%s\"} %d\n",
+				MetricsNamespace, reason, escapeLabel(prefix), byReason[reason])
+		}
+	}
+
+	writeMetricHeader(bw, "written_bytes_total", "Number of encoded bytes written.")
+	fmt.Fprintf(bw, "%s_written_bytes_total %d\n", MetricsNamespace, s.BytesWritten)
+
+	writeMetricHeader(bw, "write_errors_total", "Number of failed writes.")
+	fmt.Fprintf(bw, "%s_write_errors_total %d\n", MetricsNamespace, s.WriteErrors)
+
+	return bw.Flush()
+}
+
+// ServeHTTP реализует http.Handler, отдавая метрики в текстовом формате Prometheus
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := m.WritePrometheus(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeMetricHeader выводит строки HELP и TYPE счетчика
+func writeMetricHeader(w io.Writer, name, help string) {
+	fmt.Fprintf(w, "# HELP %s_%s %s\n# TYPE %s_%s counter\n", MetricsNamespace, name, help, MetricsNamespace, name)
+}
+
+// labelEscaper экранирует значение метки по правилам формата Prometheus
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel экранирует значение метки
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+// countRecord учитывает записанную запись в метриках логгера
+func (l *Logger) countRecord(r *Record, size int) {
+	if l.metrics != nil {
+		l.metrics.record(r.Level, r.Prefix, size)
+	}
+}
+
+// countDropped учитывает отброшенную запись в метриках логгера
+func (l *Logger) countDropped(prefix, reason string) {
+	if l.metrics != nil {
+		l.metrics.drop(prefix, reason)
+	}
+}
+
+// Metrics возвращает счетчики логгера или nil, если метрики не настроены
+func (l *Logger) Metrics() *Metrics {
+	return l.metrics
+}
+
+// WithMetrics создает новый логгер, учитывающий записи в m. Счетчики
+// разделяются с дочерними логгерами
+func (l *Logger) WithMetrics(m *Metrics) *Logger {
+	newLogger := l.clone()
+	newLogger.metrics = m
+	return newLogger
+}
@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsCountsRecords(t *testing.T) {
+	m := NewMetrics()
+	l := New().WithMetrics(m)
+	api := l.WithPrefix("API")
+
+	l.Info("started")
+	api.Error("failed")
+	api.Errorf("failed %d", 2)
+
+	s := m.Snapshot()
+	assert.Equal(t, uint64(1), s.Records[InfoLevel])
+	assert.Equal(t, uint64(2), s.Records[ErrorLevel])
+	assert.Equal(t, uint64(2), s.RecordsByPrefix["API"][ErrorLevel])
+	assert.Equal(t, uint64(1), s.RecordsByPrefix[""][InfoLevel])
+
+	var size uint64
+	for _, msg := range append(l.GetMessages(), api.GetMessages()...) {
+		size += uint64(len(msg) + 1)
+	}
+	assert.Equal(t, size, s.BytesWritten)
+	assert.Zero(t, s.WriteErrors)
+}
+
+func TestMetricsCountsDropped(t *testing.T) {
+	m := NewMetrics()
+	l := New().WithMetrics(m).
+		WithFilter(Not(MessageFilter(regexp.MustCompile("health")))).
+		WithDeduplicator(NewDeduplicator(0))
+	defer l.Close()
+	api := l.WithPrefix("API")
+
+	api.Info("health check")
+	api.Info("same")
+	api.Info("same")
+
+	s := m.Snapshot()
+	assert.Equal(t, uint64(1), s.Dropped[DropFiltered])
+	assert.Equal(t, uint64(1), s.Dropped[DropDeduplicated])
+	assert.Equal(t, uint64(1), s.DroppedByPrefix["API"][DropFiltered])
+
+	l.handleWriteError(assert.AnError)
+	assert.Equal(t, uint64(1), m.Snapshot().WriteErrors)
+}
+
+func TestMetricsCountsSampled(t *testing.T) {
+	m := NewMetrics()
+	s, _ := newTestSampler(SamplingConfig{Initial: 1})
+	l := New().WithMetrics(m).WithSampler(s).WithPrefix("API").(*Logger)
+
+	for i := 0; i < 3; i++ {
+		l.Info("hot")
+		l.Infof("hot %d", i)
+		l.Errorw("hot", "attempt", i)
+	}
+
+	snapshot := m.Snapshot()
+	assert.Equal(t, uint64(6), snapshot.Dropped[DropSampled])
+	assert.Equal(t, uint64(6), snapshot.DroppedByPrefix["API"][DropSampled])
+}
+
+func TestMetricsPrometheusHandler(t *testing.T) {
+	m := NewMetrics()
+	l := New().WithMetrics(m)
+	l.WithPrefix(`A"B`).Warning("careful")
+	m.drop("API", DropRateLimited)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE logger_records_total counter\n")
+	assert.Contains(t, body, `logger_records_total{level="WARNING",prefix="A\"B"} 1`)
+	assert.Contains(t, body, `logger_dropped_records_total{reason="rate_limited",prefix="API"} 1`)
+	assert.Contains(t, body, "logger_write_errors_total 0\n")
+}
+
+func TestConfigMetrics(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Metrics = true
+
+	l := New().WithConfig(cfg).(*Logger)
+	require.NotNil(t, l.Metrics())
+	l.Info("counted")
+	assert.Equal(t, uint64(1), l.Metrics().Snapshot().Records[InfoLevel])
+	assert.Nil(t, New().Metrics())
+}
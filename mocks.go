@@ -144,6 +144,15 @@ func (m *MockLogger) Errorf(format string, args ...interface{}) {
 	m.logf(ErrorLevel, format, args...)
 }
 
+// Errorw логирует ошибку с полями, переданными парами ключ-значение
+func (m *MockLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	fields := make(map[string]interface{}, len(keysAndValues)/2)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+	}
+	m.WithFields(fields).(*MockLogger).log(ErrorLevel, msg)
+}
+
 // Fatal логирует фатальную ошибку и завершает программу
 func (m *MockLogger) Fatal(args ...interface{}) {
 	m.log(FatalLevel, args...)
@@ -328,3 +337,23 @@ func (m *MockLogger) WithTimeFormat(format string) ILogger {
 	newLogger := m.clone()
 	return newLogger
 }
+
+// Enabled сообщает, будут ли записываться сообщения указанного уровня
+func (m *MockLogger) Enabled(level Level) bool {
+	return level >= m.getRootLogger().level
+}
+
+// DebugEnabled сообщает, включен ли уровень DEBUG
+func (m *MockLogger) DebugEnabled() bool {
+	return m.Enabled(DebugLevel)
+}
+
+// WithCaller создает новый логгер; мок не определяет место вызова
+func (m *MockLogger) WithCaller(enabled bool) ILogger {
+	return m.clone()
+}
+
+// WithCallerSkip создает новый логгер; мок не определяет место вызова
+func (m *MockLogger) WithCallerSkip(skip int) ILogger {
+	return m.clone()
+}
@@ -0,0 +1,233 @@
+package logger
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// msgpackBuffer минимальный кодировщик MessagePack для протокола Fluent Forward
+type msgpackBuffer struct {
+	b []byte
+}
+
+// put записывает байт формата и значение длины n байт в порядке big-endian
+func (m *msgpackBuffer) put(format byte, v uint64, n int) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	m.b = append(m.b, format)
+	m.b = append(m.b, buf[8-n:]...)
+}
+
+// nil записывает nil
+func (m *msgpackBuffer) nil() {
+	m.b = append(m.b, 0xc0)
+}
+
+// bool записывает логическое значение
+func (m *msgpackBuffer) bool(v bool) {
+	if v {
+		m.b = append(m.b, 0xc3)
+	} else {
+		m.b = append(m.b, 0xc2)
+	}
+}
+
+// uint записывает беззнаковое целое в наименьшем подходящем формате
+func (m *msgpackBuffer) uint(v uint64) {
+	switch {
+	case v < 0x80:
+		m.b = append(m.b, byte(v))
+	case v <= math.MaxUint8:
+		m.put(0xcc, v, 1)
+	case v <= math.MaxUint16:
+		m.put(0xcd, v, 2)
+	case v <= math.MaxUint32:
+		m.put(0xce, v, 4)
+	default:
+		m.put(0xcf, v, 8)
+	}
+}
+
+// int записывает целое в наименьшем подходящем формате
+func (m *msgpackBuffer) int(v int64) {
+	switch {
+	case v >= 0:
+		m.uint(uint64(v))
+	case v >= -32:
+		m.b = append(m.b, byte(v))
+	case v >= math.MinInt8:
+		m.put(0xd0, uint64(v), 1)
+	case v >= math.MinInt16:
+		m.put(0xd1, uint64(v), 2)
+	case v >= math.MinInt32:
+		m.put(0xd2, uint64(v), 4)
+	default:
+		m.put(0xd3, uint64(v), 8)
+	}
+}
+
+// float записывает число с плавающей точкой двойной точности
+func (m *msgpackBuffer) float(v float64) {
+	m.put(0xcb, math.Float64bits(v), 8)
+}
+
+// string записывает строку
+func (m *msgpackBuffer) string(s string) {
+	n := uint64(len(s))
+	switch {
+	case n < 32:
+		m.b = append(m.b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		m.put(0xd9, n, 1)
+	case n <= math.MaxUint16:
+		m.put(0xda, n, 2)
+	default:
+		m.put(0xdb, n, 4)
+	}
+	m.b = append(m.b, s...)
+}
+
+// binary записывает двоичные данные
+func (m *msgpackBuffer) binary(data []byte) {
+	n := uint64(len(data))
+	switch {
+	case n <= math.MaxUint8:
+		m.put(0xc4, n, 1)
+	case n <= math.MaxUint16:
+		m.put(0xc5, n, 2)
+	default:
+		m.put(0xc6, n, 4)
+	}
+	m.b = append(m.b, data...)
+}
+
+// array записывает заголовок массива из n элементов
+func (m *msgpackBuffer) array(n int) {
+	switch {
+	case n < 16:
+		m.b = append(m.b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		m.put(0xdc, uint64(n), 2)
+	default:
+		m.put(0xdd, uint64(n), 4)
+	}
+}
+
+// mapHeader записывает заголовок словаря из n пар
+func (m *msgpackBuffer) mapHeader(n int) {
+	switch {
+	case n < 16:
+		m.b = append(m.b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		m.put(0xde, uint64(n), 2)
+	default:
+		m.put(0xdf, uint64(n), 4)
+	}
+}
+
+// eventTime записывает время с наносекундами в расширении EventTime протокола
+// Fluent Forward: fixext 8 типа 0, секунды и наносекунды по 4 байта big-endian
+func (m *msgpackBuffer) eventTime(t time.Time) {
+	var buf [8]byte
+	binary.BigEndian.PutUint32(buf[:4], uint32(t.Unix()))
+	binary.BigEndian.PutUint32(buf[4:], uint32(t.Nanosecond()))
+	m.b = append(m.b, 0xd7, 0x00)
+	m.b = append(m.b, buf[:]...)
+}
+
+// value записывает произвольное значение, предварительно приведенное plainValue
+func (m *msgpackBuffer) value(v interface{}) {
+	switch v := plainValue(v).(type) {
+	case nil:
+		m.nil()
+	case bool:
+		m.bool(v)
+	case string:
+		m.string(v)
+	case []byte:
+		m.binary(v)
+	case int64:
+		m.int(v)
+	case uint64:
+		m.uint(v)
+	case float64:
+		m.float(v)
+	case []interface{}:
+		m.array(len(v))
+		for _, item := range v {
+			m.value(item)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		m.mapHeader(len(keys))
+		for _, k := range keys {
+			m.string(k)
+			m.value(v[k])
+		}
+	}
+}
+
+// readMsgpackLength читает длину из n байт в порядке big-endian
+func readMsgpackLength(r io.Reader, n int) (int, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[8-n:]); err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint64(buf[:])), nil
+}
+
+// readMsgpackMapHeader читает заголовок словаря и возвращает количество пар
+func readMsgpackMapHeader(r io.Reader) (int, error) {
+	var format [1]byte
+	if _, err := io.ReadFull(r, format[:]); err != nil {
+		return 0, err
+	}
+	switch {
+	case format[0]&0xf0 == 0x80:
+		return int(format[0] & 0x0f), nil
+	case format[0] == 0xde:
+		return readMsgpackLength(r, 2)
+	case format[0] == 0xdf:
+		return readMsgpackLength(r, 4)
+	default:
+		return 0, fmt.Errorf("ожидался словарь MessagePack, получен формат 0x%02x", format[0])
+	}
+}
+
+// readMsgpackString читает строку или двоичные данные как строку
+func readMsgpackString(r io.Reader) (string, error) {
+	var format [1]byte
+	if _, err := io.ReadFull(r, format[:]); err != nil {
+		return "", err
+	}
+	var n int
+	var err error
+	switch {
+	case format[0]&0xe0 == 0xa0:
+		n = int(format[0] & 0x1f)
+	case format[0] == 0xd9 || format[0] == 0xc4:
+		n, err = readMsgpackLength(r, 1)
+	case format[0] == 0xda || format[0] == 0xc5:
+		n, err = readMsgpackLength(r, 2)
+	case format[0] == 0xdb || format[0] == 0xc6:
+		n, err = readMsgpackLength(r, 4)
+	default:
+		return "", fmt.Errorf("ожидалась строка MessagePack, получен формат 0x%02x", format[0])
+	}
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
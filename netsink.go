@@ -0,0 +1,335 @@
+package logger
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Способы разделения записей в потоке
+const (
+	// FramingNewline завершает каждую запись переводом строки
+	FramingNewline = "newline"
+	// FramingOctetCount предваряет запись ее длиной в десятичном виде и пробелом (RFC 6587)
+	FramingOctetCount = "octet-counting"
+	// FramingLengthPrefix предваряет запись ее длиной в 4 байтах big-endian
+	FramingLengthPrefix = "length-prefix"
+	// FramingNull завершает каждую запись нулевым байтом (GELF поверх TCP)
+	FramingNull = "null"
+)
+
+// Параметры сетевого назначения по умолчанию
+const (
+	DefaultNetworkBufferSize = 1 << 20
+	DefaultNetworkTimeout    = 5 * time.Second
+	DefaultNetworkMinBackoff = 100 * time.Millisecond
+	DefaultNetworkMaxBackoff = 30 * time.Second
+)
+
+// NetworkConfig параметры сетевого назначения
+type NetworkConfig struct {
+	// Encoder кодировщик записей, по умолчанию JSONEncoder
+	Encoder Encoder
+	// TLS включает TLS поверх TCP
+	TLS *tls.Config
+	// Network тип соединения: "tcp" (по умолчанию) или "udp"
+	Network string
+	// Address адрес коллектора
+	Address string
+	// Framing способ разделения записей, по умолчанию FramingNewline.
+	// Для UDP каждая запись отправляется отдельной датаграммой
+	Framing string
+	// BufferSize объем буфера записей на время недоступности коллектора в байтах,
+	// по умолчанию DefaultNetworkBufferSize. При переполнении новые записи отбрасываются
+	BufferSize int
+	// Timeout таймаут подключения и записи, а также ожидания отправки буфера
+	// при закрытии, по умолчанию DefaultNetworkTimeout
+	Timeout time.Duration
+	// MinBackoff и MaxBackoff границы экспоненциальной паузы между попытками подключения
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// NetworkStats счетчики сетевого назначения
+type NetworkStats struct {
+	SentBytes      uint64
+	DroppedBytes   uint64
+	DroppedRecords uint64
+	Reconnects     uint64
+}
+
+// NetworkSink передает записи коллектору по TCP или UDP. Записи ставятся в очередь
+// и отправляются фоновой горутиной, которая при разрыве соединения подключается
+// заново с экспоненциальной паузой. Write не блокируется сетью
+type NetworkSink struct {
+	stats   NetworkStats // первое поле для выравнивания атомарных счетчиков на 32-битных платформах
+	cfg     NetworkConfig
+	conn    net.Conn
+	queue   [][]byte
+	queued  int
+	mu      sync.Mutex
+	cond    *sync.Cond
+	done    chan struct{}
+	abort   chan struct{}
+	closing bool
+}
+
+// NewNetworkSink создает сетевое назначение. Подключение выполняется в фоне,
+// поэтому недоступность коллектора при запуске не является ошибкой
+func NewNetworkSink(cfg NetworkConfig) (*NetworkSink, error) {
+	if cfg.Network == "" {
+		cfg.Network = "tcp"
+	}
+	if !strings.HasPrefix(cfg.Network, "tcp") && !strings.HasPrefix(cfg.Network, "udp") {
+		return nil, &ConfigError{Err: fmt.Errorf("неподдерживаемый тип соединения: %s", cfg.Network), Reason: "некорректное сетевое назначение"}
+	}
+	if cfg.TLS != nil && !strings.HasPrefix(cfg.Network, "tcp") {
+		return nil, &ConfigError{Err: fmt.Errorf("TLS недоступен для %s", cfg.Network), Reason: "некорректное сетевое назначение"}
+	}
+	if cfg.Address == "" {
+		return nil, &ConfigError{Err: fmt.Errorf("не указан адрес"), Reason: "некорректное сетевое назначение"}
+	}
+	switch cfg.Framing {
+	case "":
+		cfg.Framing = FramingNewline
+	case FramingNewline, FramingOctetCount, FramingLengthPrefix, FramingNull:
+	default:
+		return nil, &ConfigError{Err: fmt.Errorf("неизвестный способ разделения записей: %s", cfg.Framing), Reason: "некорректное сетевое назначение"}
+	}
+	if cfg.Encoder == nil {
+		cfg.Encoder = JSONEncoder{}
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = DefaultNetworkBufferSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultNetworkTimeout
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultNetworkMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = DefaultNetworkMaxBackoff
+		if cfg.MaxBackoff < cfg.MinBackoff {
+			cfg.MaxBackoff = cfg.MinBackoff
+		}
+	}
+
+	s := &NetworkSink{
+		cfg:   cfg,
+		done:  make(chan struct{}),
+		abort: make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	go s.run()
+	return s, nil
+}
+
+// Write реализует интерфейс Sink. Запись ставится в очередь; если буфер
+// переполнен, она отбрасывается и учитывается в DroppedBytes
+func (s *NetworkSink) Write(r *Record) error {
+	frame := s.frame([]byte(s.cfg.Encoder.Encode(r)))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return &WriteError{Message: "сетевое назначение закрыто"}
+	}
+	if s.queued+len(frame) > s.cfg.BufferSize {
+		atomic.AddUint64(&s.stats.DroppedBytes, uint64(len(frame)))
+		atomic.AddUint64(&s.stats.DroppedRecords, 1)
+		return &WriteError{Message: "буфер сетевого назначения переполнен"}
+	}
+	s.queue = append(s.queue, frame)
+	s.queued += len(frame)
+	s.cond.Signal()
+	return nil
+}
+
+// frame добавляет к записи разделитель выбранного вида
+func (s *NetworkSink) frame(payload []byte) []byte {
+	switch s.cfg.Framing {
+	case FramingOctetCount:
+		return append([]byte(strconv.Itoa(len(payload))+" "), payload...)
+	case FramingLengthPrefix:
+		frame := make([]byte, 4, 4+len(payload))
+		binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+		return append(frame, payload...)
+	case FramingNull:
+		return append(payload, 0)
+	default:
+		return append(payload, '\n')
+	}
+}
+
+// run отправляет записи из очереди, переподключаясь при ошибках
+func (s *NetworkSink) run() {
+	defer close(s.done)
+	backoff := s.cfg.MinBackoff
+
+	for {
+		frame, ok := s.next()
+		if !ok {
+			break
+		}
+
+		if s.conn == nil {
+			conn, err := s.dial()
+			if err != nil {
+				if !s.sleep(backoff) {
+					break
+				}
+				backoff = s.nextBackoff(backoff)
+				continue
+			}
+			s.conn = conn
+		}
+
+		if err := s.send(frame); err != nil {
+			// Запись остается в начале очереди и будет отправлена после переподключения.
+			// Пауза нужна и здесь: подключение UDP всегда успешно, а TCP-коллектор может
+			// принимать соединения и сразу их сбрасывать
+			_ = s.conn.Close()
+			s.conn = nil
+			atomic.AddUint64(&s.stats.Reconnects, 1)
+			if !s.sleep(backoff) {
+				break
+			}
+			backoff = s.nextBackoff(backoff)
+			continue
+		}
+		atomic.AddUint64(&s.stats.SentBytes, uint64(len(frame)))
+		// Пауза сбрасывается только после успешной отправки
+		backoff = s.cfg.MinBackoff
+
+		s.mu.Lock()
+		s.queue = s.queue[1:]
+		s.queued -= len(frame)
+		s.mu.Unlock()
+	}
+
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	s.dropQueue()
+}
+
+// next ожидает запись в очереди; возвращает false, если назначение закрыто
+// и очередь пуста или отправка прервана
+func (s *NetworkSink) next() ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.queue) == 0 && !s.closing {
+		s.cond.Wait()
+	}
+	select {
+	case <-s.abort:
+		return nil, false
+	default:
+	}
+	if len(s.queue) == 0 {
+		return nil, false
+	}
+	return s.queue[0], true
+}
+
+// sleep выдерживает паузу перед повторным подключением; возвращает false,
+// если отправка прервана при закрытии
+func (s *NetworkSink) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-s.abort:
+		return false
+	}
+}
+
+// nextBackoff удваивает паузу между попытками, не превышая MaxBackoff
+func (s *NetworkSink) nextBackoff(backoff time.Duration) time.Duration {
+	if backoff *= 2; backoff > s.cfg.MaxBackoff {
+		backoff = s.cfg.MaxBackoff
+	}
+	return backoff
+}
+
+// dial подключается к коллектору
+func (s *NetworkSink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+	if s.cfg.TLS != nil {
+		return tls.DialWithDialer(dialer, s.cfg.Network, s.cfg.Address, s.cfg.TLS)
+	}
+	return dialer.Dial(s.cfg.Network, s.cfg.Address)
+}
+
+// send отправляет одну запись
+func (s *NetworkSink) send(frame []byte) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.cfg.Timeout)); err != nil {
+		return err
+	}
+	_, err := s.conn.Write(frame)
+	return err
+}
+
+// dropQueue отбрасывает неотправленные записи, учитывая их в счетчиках
+func (s *NetworkSink) dropQueue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, frame := range s.queue {
+		atomic.AddUint64(&s.stats.DroppedBytes, uint64(len(frame)))
+		atomic.AddUint64(&s.stats.DroppedRecords, 1)
+	}
+	s.queue = nil
+	s.queued = 0
+}
+
+// Stats возвращает счетчики назначения
+func (s *NetworkSink) Stats() NetworkStats {
+	return NetworkStats{
+		SentBytes:      atomic.LoadUint64(&s.stats.SentBytes),
+		DroppedBytes:   atomic.LoadUint64(&s.stats.DroppedBytes),
+		DroppedRecords: atomic.LoadUint64(&s.stats.DroppedRecords),
+		Reconnects:     atomic.LoadUint64(&s.stats.Reconnects),
+	}
+}
+
+// Close реализует интерфейс Sink. Ожидает отправки буфера не дольше Timeout,
+// после чего неотправленные записи отбрасываются
+func (s *NetworkSink) Close() error {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		<-s.done
+		return nil
+	}
+	s.closing = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	timer := time.NewTimer(s.cfg.Timeout)
+	defer timer.Stop()
+	select {
+	case <-s.done:
+		return nil
+	case <-timer.C:
+	}
+
+	before := atomic.LoadUint64(&s.stats.DroppedRecords)
+	close(s.abort)
+	<-s.done
+	if dropped := atomic.LoadUint64(&s.stats.DroppedRecords) - before; dropped > 0 {
+		return &WriteError{Message: fmt.Sprintf("не удалось отправить буфер сетевого назначения, отброшено записей: %d", dropped)}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acceptLines принимает соединения и передает полученные строки в канал
+func acceptLines(ln net.Listener) <-chan string {
+	lines := make(chan string, 64)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+	return lines
+}
+
+// receive ожидает значение из канала
+func receive(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("запись не получена")
+		return ""
+	}
+}
+
+func TestNetworkSinkNewline(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	lines := acceptLines(ln)
+
+	sink, err := NewNetworkSink(NetworkConfig{Address: ln.Addr().String()})
+	require.NoError(t, err)
+
+	l := New().WithSink(sink).WithPrefix("API")
+	l.Info("first")
+	l.Error("second")
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(receive(t, lines)), &entry))
+	assert.Equal(t, "first", entry[MessageKey])
+	require.NoError(t, json.Unmarshal([]byte(receive(t, lines)), &entry))
+	assert.Equal(t, "second", entry[MessageKey])
+
+	require.NoError(t, l.Close())
+	stats := sink.Stats()
+	assert.Positive(t, stats.SentBytes)
+	assert.Zero(t, stats.DroppedBytes)
+	assert.Error(t, sink.Write(&Record{Message: "late"}))
+}
+
+func TestNetworkSinkFraming(t *testing.T) {
+	payload := []byte("hello\nworld")
+
+	s := &NetworkSink{cfg: NetworkConfig{Framing: FramingOctetCount}}
+	assert.Equal(t, "11 hello\nworld", string(s.frame(append([]byte{}, payload...))))
+
+	s.cfg.Framing = FramingLengthPrefix
+	frame := s.frame(append([]byte{}, payload...))
+	assert.Equal(t, uint32(11), binary.BigEndian.Uint32(frame))
+	assert.Equal(t, payload, frame[4:])
+
+	_, err := NewNetworkSink(NetworkConfig{Address: "localhost:1", Framing: "crlf"})
+	var cfgErr *ConfigError
+	assert.ErrorAs(t, err, &cfgErr)
+	_, err = NewNetworkSink(NetworkConfig{Network: "udp", Address: "localhost:1", TLS: &tls.Config{}})
+	assert.ErrorAs(t, err, &cfgErr)
+}
+
+func TestNetworkSinkOctetCountingOverTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		size, _ := r.ReadString(' ')
+		n, _ := strconv.Atoi(strings.TrimSpace(size))
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err == nil {
+			received <- string(msg)
+		}
+	}()
+
+	sink, err := NewNetworkSink(NetworkConfig{
+		Address: ln.Addr().String(),
+		Framing: FramingOctetCount,
+		Encoder: TextEncoder{Control: ControlRaw},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: "multi\nline"}))
+	assert.Contains(t, receive(t, received), "multi\nline")
+}
+
+func TestNetworkSinkBuffersDuringOutage(t *testing.T) {
+	// Резервируем свободный порт, на котором коллектор появится позже
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	sink, err := NewNetworkSink(NetworkConfig{
+		Address:    addr,
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: "buffered"}))
+	time.Sleep(30 * time.Millisecond)
+
+	ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	defer ln.Close()
+	lines := acceptLines(ln)
+
+	assert.Contains(t, receive(t, lines), `"msg":"buffered"`)
+}
+
+func TestNetworkSinkDropsOnOverflow(t *testing.T) {
+	sink, err := NewNetworkSink(NetworkConfig{
+		Address:    "127.0.0.1:1",
+		BufferSize: 64,
+		Timeout:    50 * time.Millisecond,
+		MinBackoff: time.Hour,
+	})
+	require.NoError(t, err)
+
+	var writeErr *WriteError
+	for i := 0; i < 10; i++ {
+		err = sink.Write(&Record{Level: InfoLevel, Message: "overflow"})
+	}
+	assert.ErrorAs(t, err, &writeErr)
+	before := sink.Stats()
+	assert.Positive(t, before.DroppedBytes)
+	assert.Positive(t, before.DroppedRecords)
+
+	// При закрытии неотправленный буфер отбрасывается по таймауту
+	start := time.Now()
+	assert.ErrorAs(t, sink.Close(), &writeErr)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Greater(t, sink.Stats().DroppedRecords, before.DroppedRecords)
+	assert.NoError(t, sink.Close())
+}
+
+func TestNetworkSinkBackoffAfterSendFailure(t *testing.T) {
+	// Подключение UDP всегда успешно, а запись в закрытый порт завершается ошибкой
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := pc.LocalAddr().String()
+	require.NoError(t, pc.Close())
+
+	sink, err := NewNetworkSink(NetworkConfig{
+		Network:    "udp",
+		Address:    addr,
+		Timeout:    50 * time.Millisecond,
+		MinBackoff: 20 * time.Millisecond,
+		MaxBackoff: 40 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		_ = sink.Write(&Record{Level: InfoLevel, Message: "unreachable"})
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	// Между переподключениями выдерживается пауза, горутина не крутится вхолостую
+	assert.LessOrEqual(t, sink.Stats().Reconnects, uint64(15))
+	_ = sink.Close()
+}
+
+func TestNetworkSinkUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	sink, err := NewNetworkSink(NetworkConfig{Network: "udp", Address: pc.LocalAddr().String()})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write(&Record{Level: WarningLevel, Message: "datagram"}))
+	buf := make([]byte, 1024)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(buf[:n]), "\"msg\":\"datagram\"}\n"))
+}
+
+func TestNetworkSinkTLS(t *testing.T) {
+	// Сертификат тестового HTTPS-сервера используется для собственного TLS-слушателя
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", srv.TLS)
+	require.NoError(t, err)
+	defer ln.Close()
+	lines := acceptLines(ln)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	sink, err := NewNetworkSink(NetworkConfig{
+		Address: ln.Addr().String(),
+		TLS:     &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write(&Record{Level: InfoLevel, Message: "secure"}))
+	assert.Contains(t, receive(t, lines), `"msg":"secure"`)
+}
@@ -0,0 +1,499 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OTLPScopeName имя области инструментирования записей без префикса
+const OTLPScopeName = "github.com/ipiton/logger"
+
+// OTLPConfig параметры экспорта записей по протоколу OTLP/HTTP
+type OTLPConfig struct {
+	BatchConfig
+	RetryConfig
+	// Client HTTP-клиент, по умолчанию клиент с таймаутом DefaultHTTPTimeout
+	Client *http.Client
+	// Headers дополнительные заголовки запросов, например Authorization
+	Headers map[string]string
+	// ResourceAttributes дополнительные атрибуты ресурса, например deployment.environment
+	ResourceAttributes map[string]interface{}
+	// URL адрес приема логов, например http://collector:4318/v1/logs
+	URL string
+	// ServiceName значение service.name, по умолчанию имя исполняемого файла
+	ServiceName string
+	// ServiceVersion значение service.version, по умолчанию Version
+	ServiceVersion string
+	// JSON включает кодирование OTLP/JSON вместо protobuf
+	JSON bool
+	// Gzip включает сжатие тела запроса
+	Gzip bool
+}
+
+// OTLPSink экспортирует записи в коллектор OpenTelemetry как LogRecord.
+// Префикс записи становится именем области инструментирования, поля записи
+// атрибутами; поля TraceIDKey и SpanIDKey в шестнадцатеричном виде заполняют
+// контекст трассировки
+type OTLPSink struct {
+	*batchSender
+	cfg      OTLPConfig
+	resource []otlpAttribute
+}
+
+// otlpAttribute атрибут записи или ресурса
+type otlpAttribute struct {
+	Value interface{}
+	Key   string
+}
+
+// NewOTLPSink создает экспорт OTLP
+func NewOTLPSink(cfg OTLPConfig) (*OTLPSink, error) {
+	if cfg.URL == "" {
+		return nil, &ConfigError{Err: fmt.Errorf("не указан URL"), Reason: "некорректное назначение OTLP"}
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = filepath.Base(os.Args[0])
+	}
+	if cfg.ServiceVersion == "" {
+		cfg.ServiceVersion = Version
+	}
+
+	resource := []otlpAttribute{
+		{Key: "service.name", Value: cfg.ServiceName},
+		{Key: "service.version", Value: cfg.ServiceVersion},
+		{Key: "vcs.ref.head.revision", Value: CommitHash},
+	}
+	keys := make([]string, 0, len(cfg.ResourceAttributes))
+	for k := range cfg.ResourceAttributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		resource = append(resource, otlpAttribute{Key: k, Value: cfg.ResourceAttributes[k]})
+	}
+
+	s := &OTLPSink{cfg: cfg, resource: resource}
+	s.batchSender = newBatchSender("otlp", cfg.BatchConfig, cfg.Client, cfg.RetryConfig, s.request)
+	s.response = s.partialSuccess
+	return s, nil
+}
+
+// Write реализует интерфейс Sink. Запись кодируется в LogRecord сразу,
+// чтобы размер пакета учитывал итоговый объем
+func (s *OTLPSink) Write(r *Record) error {
+	if s.cfg.JSON {
+		return s.add(batchEntry{record: r, data: encodeOTLPRecordJSON(r)})
+	}
+	return s.add(batchEntry{record: r, data: encodeOTLPRecordProto(r)})
+}
+
+// otlpSeverity возвращает номер серьезности OpenTelemetry для уровня
+func otlpSeverity(level Level) uint64 {
+	switch level {
+	case DebugLevel:
+		return 5
+	case InfoLevel:
+		return 9
+	case WarningLevel:
+		return 13
+	case ErrorLevel:
+		return 17
+	default:
+		return 21
+	}
+}
+
+// otlpScope возвращает имя области инструментирования записи
+func otlpScope(r *Record) string {
+	if r.Prefix == "" {
+		return OTLPScopeName
+	}
+	return r.Prefix
+}
+
+// otlpRecordAttributes возвращает атрибуты записи по семантическим соглашениям
+// OpenTelemetry и контекст трассировки, если он есть в полях
+func otlpRecordAttributes(r *Record) ([]otlpAttribute, []byte, []byte) {
+	var attrs []otlpAttribute
+	var traceID, spanID []byte
+	if r.Caller != nil {
+		attrs = append(attrs,
+			otlpAttribute{Key: "code.filepath", Value: r.Caller.File},
+			otlpAttribute{Key: "code.lineno", Value: r.Caller.Line},
+		)
+		if r.Caller.Function != "" {
+			attrs = append(attrs, otlpAttribute{Key: "code.function", Value: r.Caller.Function})
+		}
+	}
+	for _, k := range sortedKeys(r.Fields) {
+		v := r.Fields[k]
+		switch k {
+		case TraceIDKey:
+			if id := otlpID(v, 16); id != nil {
+				traceID = id
+				continue
+			}
+		case SpanIDKey:
+			if id := otlpID(v, 8); id != nil {
+				spanID = id
+				continue
+			}
+		case ErrorKey:
+			if err, ok := v.(error); ok && err != nil {
+				info := describeError(err, 0)
+				attrs = append(attrs,
+					otlpAttribute{Key: "exception.message", Value: info.Message},
+					otlpAttribute{Key: "exception.type", Value: info.Type},
+				)
+				continue
+			}
+		}
+		attrs = append(attrs, otlpAttribute{Key: k, Value: v})
+	}
+	if r.Stack != "" {
+		attrs = append(attrs, otlpAttribute{Key: "exception.stacktrace", Value: r.Stack})
+	}
+	return attrs, traceID, spanID
+}
+
+// otlpID разбирает идентификатор трассировки или операции из шестнадцатеричной
+// строки или байтов. Возвращает nil, если длина не совпадает или идентификатор нулевой
+func otlpID(v interface{}, size int) []byte {
+	var id []byte
+	switch v := v.(type) {
+	case string:
+		decoded, err := hex.DecodeString(v)
+		if err != nil {
+			return nil
+		}
+		id = decoded
+	case []byte:
+		id = v
+	case fmt.Stringer:
+		return otlpID(v.String(), size)
+	default:
+		return nil
+	}
+	if len(id) != size || bytes.Count(id, []byte{0}) == size {
+		return nil
+	}
+	return id
+}
+
+// encodeOTLPRecordProto кодирует запись в сообщение LogRecord:
+//
+//	LogRecord { fixed64 time_unix_nano = 1; SeverityNumber severity_number = 2;
+//	            string severity_text = 3; AnyValue body = 5; repeated KeyValue attributes = 6;
+//	            bytes trace_id = 9; bytes span_id = 10; fixed64 observed_time_unix_nano = 11; }
+func encodeOTLPRecordProto(r *Record) []byte {
+	attrs, traceID, spanID := otlpRecordAttributes(r)
+
+	var p protoBuffer
+	p.fixed64Field(1, uint64(r.Time.UnixNano()))
+	p.uint64Field(2, otlpSeverity(r.Level))
+	p.stringField(3, r.Level.String())
+	p.message(5, func(m *protoBuffer) { otlpProtoValue(m, r.Message) })
+	otlpProtoAttributes(&p, 6, attrs)
+	p.bytesField(9, traceID)
+	p.bytesField(10, spanID)
+	p.fixed64Field(11, uint64(r.Time.UnixNano()))
+	return p.b
+}
+
+// otlpProtoAttributes записывает атрибуты повторяющимся полем KeyValue { string key = 1; AnyValue value = 2; }
+func otlpProtoAttributes(p *protoBuffer, field int, attrs []otlpAttribute) {
+	for _, a := range attrs {
+		a := a
+		p.message(field, func(kv *protoBuffer) {
+			kv.stringField(1, a.Key)
+			kv.message(2, func(m *protoBuffer) { otlpProtoValue(m, a.Value) })
+		})
+	}
+}
+
+// otlpProtoValue записывает содержимое AnyValue. Поля oneof записываются
+// и с нулевыми значениями, иначе значение будет прочитано как пустое
+func otlpProtoValue(p *protoBuffer, v interface{}) {
+	switch v := plainValue(v).(type) {
+	case string:
+		p.tag(1, protoBytes)
+		p.varint(uint64(len(v)))
+		p.b = append(p.b, v...)
+	case bool:
+		p.tag(2, protoVarint)
+		if v {
+			p.varint(1)
+		} else {
+			p.varint(0)
+		}
+	case int64:
+		p.tag(3, protoVarint)
+		p.varint(uint64(v))
+	case uint64:
+		if v > math.MaxInt64 {
+			otlpProtoValue(p, float64(v))
+			return
+		}
+		otlpProtoValue(p, int64(v))
+	case float64:
+		p.tag(4, protoFixed64)
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+		p.b = append(p.b, buf[:]...)
+	case []interface{}:
+		p.message(5, func(arr *protoBuffer) {
+			for _, item := range v {
+				item := item
+				arr.message(1, func(m *protoBuffer) { otlpProtoValue(m, item) })
+			}
+		})
+	case map[string]interface{}:
+		p.message(6, func(list *protoBuffer) {
+			for _, k := range sortedKeys(v) {
+				otlpProtoAttributes(list, 1, []otlpAttribute{{Key: k, Value: v[k]}})
+			}
+		})
+	case []byte:
+		p.tag(7, protoBytes)
+		p.varint(uint64(len(v)))
+		p.b = append(p.b, v...)
+	}
+}
+
+// encodeOTLPRecordJSON кодирует запись в объект LogRecord формата OTLP/JSON.
+// Время и целые числа записываются строками, идентификаторы трассировки в hex
+func encodeOTLPRecordJSON(r *Record) []byte {
+	attrs, traceID, spanID := otlpRecordAttributes(r)
+	ts := strconv.FormatInt(r.Time.UnixNano(), 10)
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	writeJSONField(&b, "timeUnixNano", ts, true)
+	writeJSONField(&b, "observedTimeUnixNano", ts, false)
+	writeJSONField(&b, "severityNumber", otlpSeverity(r.Level), false)
+	writeJSONField(&b, "severityText", r.Level.String(), false)
+	b.WriteString(`,"body":`)
+	otlpJSONValue(&b, r.Message)
+	if len(attrs) > 0 {
+		b.WriteString(`,"attributes":`)
+		otlpJSONAttributes(&b, attrs)
+	}
+	if traceID != nil {
+		writeJSONField(&b, "traceId", hex.EncodeToString(traceID), false)
+	}
+	if spanID != nil {
+		writeJSONField(&b, "spanId", hex.EncodeToString(spanID), false)
+	}
+	b.WriteByte('}')
+	return b.Bytes()
+}
+
+// otlpJSONAttributes записывает массив KeyValue
+func otlpJSONAttributes(b *bytes.Buffer, attrs []otlpAttribute) {
+	b.WriteByte('[')
+	for i, a := range attrs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('{')
+		writeJSONField(b, "key", a.Key, true)
+		b.WriteString(`,"value":`)
+		otlpJSONValue(b, a.Value)
+		b.WriteByte('}')
+	}
+	b.WriteByte(']')
+}
+
+// otlpJSONValue записывает AnyValue
+func otlpJSONValue(b *bytes.Buffer, v interface{}) {
+	switch v := plainValue(v).(type) {
+	case nil:
+		b.WriteString("{}")
+	case string:
+		b.WriteString(`{"stringValue":`)
+		b.Write(marshalJSON(v))
+		b.WriteByte('}')
+	case bool:
+		b.WriteString(`{"boolValue":` + strconv.FormatBool(v) + "}")
+	case int64:
+		b.WriteString(`{"intValue":"` + strconv.FormatInt(v, 10) + `"}`)
+	case uint64:
+		if v > math.MaxInt64 {
+			otlpJSONValue(b, float64(v))
+			return
+		}
+		otlpJSONValue(b, int64(v))
+	case float64:
+		b.WriteString(`{"doubleValue":`)
+		b.Write(otlpJSONDouble(v))
+		b.WriteByte('}')
+	case []interface{}:
+		b.WriteString(`{"arrayValue":{"values":[`)
+		for i, item := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			otlpJSONValue(b, item)
+		}
+		b.WriteString("]}}")
+	case map[string]interface{}:
+		attrs := make([]otlpAttribute, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			attrs = append(attrs, otlpAttribute{Key: k, Value: v[k]})
+		}
+		b.WriteString(`{"kvlistValue":{"values":`)
+		otlpJSONAttributes(b, attrs)
+		b.WriteString("}}")
+	case []byte:
+		b.WriteString(`{"bytesValue":"` + base64.StdEncoding.EncodeToString(v) + `"}`)
+	}
+}
+
+// otlpJSONDouble кодирует число с плавающей точкой; NaN и бесконечности
+// записываются строками, как в отображении protobuf в JSON
+func otlpJSONDouble(v float64) []byte {
+	switch {
+	case math.IsNaN(v):
+		return []byte(`"NaN"`)
+	case math.IsInf(v, 1):
+		return []byte(`"Infinity"`)
+	case math.IsInf(v, -1):
+		return []byte(`"-Infinity"`)
+	}
+	return marshalJSON(v)
+}
+
+// otlpScopes группирует записи пакета по областям инструментирования в порядке появления
+func otlpScopes(batch []batchEntry) ([]string, map[string][]batchEntry) {
+	var names []string
+	scopes := make(map[string][]batchEntry)
+	for _, e := range batch {
+		name := otlpScope(e.record)
+		if _, ok := scopes[name]; !ok {
+			names = append(names, name)
+		}
+		scopes[name] = append(scopes[name], e)
+	}
+	return names, scopes
+}
+
+// request строит запрос экспорта ExportLogsServiceRequest:
+//
+//	ExportLogsServiceRequest { repeated ResourceLogs resource_logs = 1; }
+//	ResourceLogs { Resource resource = 1; repeated ScopeLogs scope_logs = 2; }
+//	ScopeLogs    { InstrumentationScope scope = 1; repeated LogRecord log_records = 2; }
+func (s *OTLPSink) request(batch []batchEntry) (func() (*http.Request, error), error) {
+	names, scopes := otlpScopes(batch)
+
+	if s.cfg.JSON {
+		var b bytes.Buffer
+		b.WriteString(`{"resourceLogs":[{"resource":{"attributes":`)
+		otlpJSONAttributes(&b, s.resource)
+		b.WriteString(`},"scopeLogs":[`)
+		for i, name := range names {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(`{"scope":{`)
+			writeJSONField(&b, "name", name, true)
+			writeJSONField(&b, "version", Version, false)
+			b.WriteString(`},"logRecords":[`)
+			for j, e := range scopes[name] {
+				if j > 0 {
+					b.WriteByte(',')
+				}
+				b.Write(e.data)
+			}
+			b.WriteString("]}")
+		}
+		b.WriteString("]}]}")
+		return newBodyRequest(http.MethodPost, s.cfg.URL, "application/json", s.cfg.Headers, b.Bytes(), s.cfg.Gzip)
+	}
+
+	var p protoBuffer
+	p.message(1, func(rl *protoBuffer) {
+		rl.message(1, func(res *protoBuffer) { otlpProtoAttributes(res, 1, s.resource) })
+		for _, name := range names {
+			name := name
+			rl.message(2, func(sl *protoBuffer) {
+				sl.message(1, func(scope *protoBuffer) {
+					scope.stringField(1, name)
+					scope.stringField(2, Version)
+				})
+				for _, e := range scopes[name] {
+					sl.bytesField(2, e.data)
+				}
+			})
+		}
+	})
+	return newBodyRequest(http.MethodPost, s.cfg.URL, "application/x-protobuf", s.cfg.Headers, p.b, s.cfg.Gzip)
+}
+
+// partialSuccess разбирает ответ ExportLogsServiceResponse и возвращает
+// количество записей, отвергнутых коллектором:
+//
+//	ExportLogsServiceResponse { ExportLogsPartialSuccess partial_success = 1; }
+//	ExportLogsPartialSuccess  { int64 rejected_log_records = 1; string error_message = 2; }
+func (s *OTLPSink) partialSuccess(body []byte, batch []batchEntry) (int, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return 0, nil
+	}
+
+	var rejected int64
+	var message string
+	if s.cfg.JSON {
+		var resp struct {
+			PartialSuccess struct {
+				RejectedLogRecords json.Number `json:"rejectedLogRecords"`
+				ErrorMessage       string      `json:"errorMessage"`
+			} `json:"partialSuccess"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return 0, fmt.Errorf("некорректный ответ OTLP: %w", err)
+		}
+		if resp.PartialSuccess.RejectedLogRecords != "" {
+			rejected, _ = resp.PartialSuccess.RejectedLogRecords.Int64()
+		}
+		message = resp.PartialSuccess.ErrorMessage
+	} else {
+		err := protoScan(body, func(field int, _ uint64, data []byte) error {
+			if field != 1 {
+				return nil
+			}
+			return protoScan(data, func(field int, v uint64, data []byte) error {
+				switch field {
+				case 1:
+					rejected = int64(v)
+				case 2:
+					message = string(data)
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return 0, fmt.Errorf("некорректный ответ OTLP: %w", err)
+		}
+	}
+
+	if rejected <= 0 && message == "" {
+		return 0, nil
+	}
+	if rejected <= 0 {
+		return 0, fmt.Errorf("предупреждение коллектора: %s", strings.TrimSpace(message))
+	}
+	if rejected > int64(len(batch)) {
+		rejected = int64(len(batch))
+	}
+	return int(rejected), fmt.Errorf("коллектор отверг записей: %d: %s", rejected, strings.TrimSpace(message))
+}
@@ -0,0 +1,228 @@
+package logger
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// otlpCollector фейковый коллектор, сохраняющий тела запросов
+type otlpCollector struct {
+	*httptest.Server
+	mu          sync.Mutex
+	bodies      [][]byte
+	contentType string
+	response    []byte
+}
+
+func newOTLPCollector(t *testing.T, response []byte) *otlpCollector {
+	c := &otlpCollector{response: response}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.bodies = append(c.bodies, body)
+		c.contentType = r.Header.Get("Content-Type")
+		_, _ = w.Write(c.response)
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// otlpKeyValues разбирает повторяющиеся KeyValue в словарь ключ -> AnyValue
+func otlpKeyValues(t *testing.T, kvs [][]byte) map[string]map[int][][]byte {
+	t.Helper()
+	result := make(map[string]map[int][][]byte)
+	for _, kv := range kvs {
+		fields := protoDecode(t, kv)
+		result[string(fields[1][0])] = protoDecode(t, fields[2][0])
+	}
+	return result
+}
+
+func TestOTLPSinkProtobuf(t *testing.T) {
+	collector := newOTLPCollector(t, nil)
+
+	sink, err := NewOTLPSink(OTLPConfig{
+		URL:                collector.URL + "/v1/logs",
+		ServiceName:        "billing",
+		ResourceAttributes: map[string]interface{}{"deployment.environment": "prod"},
+	})
+	require.NoError(t, err)
+
+	l := New().WithSink(sink).WithCaller(true)
+	l.WithPrefix("API").WithFields(Fields{
+		TraceIDKey: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanIDKey:  "00f067aa0ba902b7",
+		"attempt":  0,
+		"cached":   false,
+		"ratio":    0.5,
+		"tags":     []string{"a"},
+		ErrorKey:   errors.New("boom"),
+	}).Error("failed")
+	l.Info("plain")
+	require.NoError(t, l.Close())
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	require.Len(t, collector.bodies, 1)
+	assert.Equal(t, "application/x-protobuf", collector.contentType)
+
+	request := protoDecode(t, collector.bodies[0])
+	require.Len(t, request[1], 1)
+	resourceLogs := protoDecode(t, request[1][0])
+
+	resource := otlpKeyValues(t, protoDecode(t, resourceLogs[1][0])[1])
+	assert.Equal(t, "billing", string(resource["service.name"][1][0]))
+	assert.Equal(t, Version, string(resource["service.version"][1][0]))
+	assert.Equal(t, CommitHash, string(resource["vcs.ref.head.revision"][1][0]))
+	assert.Equal(t, "prod", string(resource["deployment.environment"][1][0]))
+
+	// Записи группируются по префиксу в области инструментирования
+	require.Len(t, resourceLogs[2], 2)
+	scopeLogs := protoDecode(t, resourceLogs[2][0])
+	scope := protoDecode(t, scopeLogs[1][0])
+	assert.Equal(t, "API", string(scope[1][0]))
+	assert.Equal(t, OTLPScopeName, string(protoDecode(t, protoDecode(t, resourceLogs[2][1])[1][0])[1][0]))
+
+	require.Len(t, scopeLogs[2], 1)
+	record := protoDecode(t, scopeLogs[2][0])
+	assert.InDelta(t, float64(time.Now().UnixNano()), float64(binary.LittleEndian.Uint64(record[1][0])), float64(time.Minute))
+	assert.Equal(t, []byte{17}, record[2][0])
+	assert.Equal(t, "ERROR", string(record[3][0]))
+	assert.Equal(t, "failed", string(protoDecode(t, record[5][0])[1][0]))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(record[9][0]))
+	assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(record[10][0]))
+
+	attrs := otlpKeyValues(t, record[6])
+	assert.NotContains(t, attrs, TraceIDKey)
+	assert.NotContains(t, attrs, SpanIDKey)
+	assert.Equal(t, []byte{0}, attrs["attempt"][3][0])
+	assert.Equal(t, []byte{0}, attrs["cached"][2][0])
+	assert.Equal(t, 0.5, math.Float64frombits(binary.LittleEndian.Uint64(attrs["ratio"][4][0])))
+	assert.Equal(t, "a", string(protoDecode(t, protoDecode(t, attrs["tags"][5][0])[1][0])[1][0]))
+	assert.Equal(t, "boom", string(attrs["exception.message"][1][0]))
+	assert.Equal(t, "*errors.errorString", string(attrs["exception.type"][1][0]))
+	assert.Contains(t, string(attrs["code.filepath"][1][0]), "otlp_test.go")
+	assert.Contains(t, attrs, "code.lineno")
+
+	assert.Equal(t, uint64(2), sink.Stats().SentRecords)
+}
+
+func TestOTLPSinkJSON(t *testing.T) {
+	collector := newOTLPCollector(t, []byte(`{}`))
+
+	sink, err := NewOTLPSink(OTLPConfig{URL: collector.URL + "/v1/logs", ServiceName: "billing", JSON: true})
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Write(&Record{
+		Time:    time.Unix(1714564800, 5),
+		Level:   WarningLevel,
+		Prefix:  "DB",
+		Message: "slow",
+		Fields: map[string]interface{}{
+			"ms":         int64(1500),
+			"meta":       map[string]interface{}{"shard": 2},
+			TraceIDKey:   "not-hex",
+			"nil":        nil,
+			"user.admin": true,
+		},
+	}))
+	require.NoError(t, sink.Close())
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	require.Len(t, collector.bodies, 1)
+	assert.Equal(t, "application/json", collector.contentType)
+
+	var request struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []map[string]interface{} `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				Scope      map[string]string        `json:"scope"`
+				LogRecords []map[string]interface{} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	require.NoError(t, json.Unmarshal(collector.bodies[0], &request))
+	require.Len(t, request.ResourceLogs, 1)
+	assert.Equal(t, map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "billing"}},
+		request.ResourceLogs[0].Resource.Attributes[0])
+
+	scopeLogs := request.ResourceLogs[0].ScopeLogs
+	require.Len(t, scopeLogs, 1)
+	assert.Equal(t, "DB", scopeLogs[0].Scope["name"])
+	require.Len(t, scopeLogs[0].LogRecords, 1)
+
+	record := scopeLogs[0].LogRecords[0]
+	assert.Equal(t, "1714564800000000005", record["timeUnixNano"])
+	assert.Equal(t, float64(13), record["severityNumber"])
+	assert.Equal(t, "WARNING", record["severityText"])
+	assert.Equal(t, map[string]interface{}{"stringValue": "slow"}, record["body"])
+	assert.NotContains(t, record, "traceId")
+
+	attrs := map[string]interface{}{}
+	for _, a := range record["attributes"].([]interface{}) {
+		kv := a.(map[string]interface{})
+		attrs[kv["key"].(string)] = kv["value"]
+	}
+	assert.Equal(t, map[string]interface{}{"intValue": "1500"}, attrs["ms"])
+	assert.Equal(t, map[string]interface{}{"boolValue": true}, attrs["user.admin"])
+	assert.Equal(t, map[string]interface{}{}, attrs["nil"])
+	// Некорректный идентификатор трассировки остается обычным атрибутом
+	assert.Equal(t, map[string]interface{}{"stringValue": "not-hex"}, attrs[TraceIDKey])
+	assert.Equal(t, map[string]interface{}{"kvlistValue": map[string]interface{}{"values": []interface{}{
+		map[string]interface{}{"key": "shard", "value": map[string]interface{}{"intValue": "2"}},
+	}}}, attrs["meta"])
+}
+
+func TestOTLPSinkPartialSuccess(t *testing.T) {
+	var response protoBuffer
+	response.message(1, func(m *protoBuffer) {
+		m.int64Field(1, 1)
+		m.stringField(2, "invalid record")
+	})
+
+	for name, cfg := range map[string]struct {
+		response []byte
+		json     bool
+	}{
+		"protobuf": {response: response.b},
+		"json":     {response: []byte(`{"partialSuccess":{"rejectedLogRecords":"1","errorMessage":"invalid record"}}`), json: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			collector := newOTLPCollector(t, cfg.response)
+			sink, err := NewOTLPSink(OTLPConfig{URL: collector.URL + "/v1/logs", JSON: cfg.json})
+			require.NoError(t, err)
+
+			require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: InfoLevel, Message: "first"}))
+			require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: InfoLevel, Message: "second"}))
+			assert.Error(t, sink.Close())
+
+			stats := sink.Stats()
+			assert.Equal(t, uint64(1), stats.SentRecords)
+			assert.Equal(t, uint64(1), stats.DroppedRecords)
+		})
+	}
+
+	_, err := NewOTLPSink(OTLPConfig{})
+	var cfgErr *ConfigError
+	assert.ErrorAs(t, err, &cfgErr)
+}
@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Типы кодирования полей protobuf
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// protoBuffer минимальный кодировщик protobuf для протоколов, которые не стоит
+// тянуть внешней зависимостью. Нулевые скалярные значения не записываются, как в proto3
+type protoBuffer struct {
+	b []byte
+}
+
+// varint записывает число в формате varint
+func (p *protoBuffer) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	p.b = append(p.b, buf[:n]...)
+}
+
+// tag записывает номер поля и тип кодирования
+func (p *protoBuffer) tag(field, wire int) {
+	p.varint(uint64(field)<<3 | uint64(wire))
+}
+
+// uint64Field записывает беззнаковое целое поле
+func (p *protoBuffer) uint64Field(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	p.tag(field, protoVarint)
+	p.varint(v)
+}
+
+// int64Field записывает целое поле; отрицательные значения занимают 10 байт, как в protobuf
+func (p *protoBuffer) int64Field(field int, v int64) {
+	p.uint64Field(field, uint64(v))
+}
+
+// fixed64Field записывает поле fixed64
+func (p *protoBuffer) fixed64Field(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	p.tag(field, protoFixed64)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	p.b = append(p.b, buf[:]...)
+}
+
+// stringField записывает строковое поле
+func (p *protoBuffer) stringField(field int, s string) {
+	if s == "" {
+		return
+	}
+	p.tag(field, protoBytes)
+	p.varint(uint64(len(s)))
+	p.b = append(p.b, s...)
+}
+
+// bytesField записывает поле с байтами
+func (p *protoBuffer) bytesField(field int, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	p.tag(field, protoBytes)
+	p.varint(uint64(len(data)))
+	p.b = append(p.b, data...)
+}
+
+// message записывает вложенное сообщение; пустое сообщение записывается,
+// чтобы сохранить факт присутствия поля
+func (p *protoBuffer) message(field int, encode func(m *protoBuffer)) {
+	var m protoBuffer
+	encode(&m)
+	p.tag(field, protoBytes)
+	p.varint(uint64(len(m.b)))
+	p.b = append(p.b, m.b...)
+}
+
+// protoScan перебирает поля сообщения protobuf. Для varint и fixed полей
+// передается числовое значение, для полей с длиной их содержимое
+func protoScan(data []byte, visit func(field int, v uint64, data []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("некорректный номер поля")
+		}
+		data = data[n:]
+
+		var v uint64
+		var value []byte
+		switch key & 7 {
+		case protoVarint:
+			if v, n = binary.Uvarint(data); n <= 0 {
+				return errors.New("некорректное значение varint")
+			}
+			data = data[n:]
+		case protoFixed64:
+			if len(data) < 8 {
+				return io.ErrUnexpectedEOF
+			}
+			v = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case protoFixed32:
+			if len(data) < 4 {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case protoBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return io.ErrUnexpectedEOF
+			}
+			value = data[n : n+int(size)]
+			data = data[n+int(size):]
+		default:
+			return fmt.Errorf("неподдерживаемый тип поля %d", key&7)
+		}
+		if err := visit(int(key>>3), v, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
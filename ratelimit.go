@@ -0,0 +1,205 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig описывает жесткие ограничения потока записей для каждого префикса.
+// В отличие от сэмплирования, ограничения не зависят от текста сообщений и защищают
+// общие назначения от одной шумной подсистемы
+type RateLimitConfig struct {
+	// Records допустимое количество записей в секунду на префикс; 0 снимает ограничение
+	Records float64
+	// Bytes допустимый объем закодированных записей в байтах в секунду на префикс; 0 снимает ограничение
+	Bytes float64
+	// RecordsBurst максимальный запас записей, по умолчанию равен Records
+	RecordsBurst float64
+	// BytesBurst максимальный запас байт, по умолчанию равен Bytes
+	BytesBurst float64
+	// ExemptErrors освобождает записи уровней ERROR и FATAL от ограничений
+	ExemptErrors bool
+}
+
+// RateLimiter ограничивает записи по префиксам с помощью token bucket
+type RateLimiter struct {
+	now     func() time.Time
+	buckets map[string]*prefixBuckets
+	cfg     RateLimitConfig
+	mu      sync.Mutex
+}
+
+// prefixBuckets состояние ограничителя для одного префикса
+type prefixBuckets struct {
+	updated time.Time
+	records float64
+	bytes   float64
+	dropped uint64
+	pending uint64
+}
+
+// rateLimitSummary сводка об отброшенных записях одного префикса
+type rateLimitSummary struct {
+	prefix  string
+	dropped uint64
+}
+
+// NewRateLimiter создает ограничитель по конфигурации
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	if cfg.RecordsBurst <= 0 {
+		cfg.RecordsBurst = cfg.Records
+	}
+	if cfg.BytesBurst <= 0 {
+		cfg.BytesBurst = cfg.Bytes
+	}
+	return &RateLimiter{
+		cfg:     cfg,
+		buckets: make(map[string]*prefixBuckets),
+		now:     time.Now,
+	}
+}
+
+// Dropped возвращает общее количество отброшенных ограничителем записей
+func (rl *RateLimiter) Dropped() uint64 {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	var total uint64
+	for _, b := range rl.buckets {
+		total += b.dropped
+	}
+	return total
+}
+
+// DroppedByPrefix возвращает количество отброшенных записей по префиксам
+func (rl *RateLimiter) DroppedByPrefix() map[string]uint64 {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	dropped := make(map[string]uint64, len(rl.buckets))
+	for prefix, b := range rl.buckets {
+		if b.dropped > 0 {
+			dropped[prefix] = b.dropped
+		}
+	}
+	return dropped
+}
+
+// allow проверяет, укладывается ли запись размером size байт в ограничения префикса.
+// Когда префикс снова укладывается в ограничения после отбрасывания записей,
+// возвращается сводка для вывода перед текущей записью
+func (rl *RateLimiter) allow(prefix string, level Level, size int) (bool, *rateLimitSummary) {
+	if rl.cfg.ExemptErrors && level >= ErrorLevel {
+		return true, nil
+	}
+
+	now := rl.now()
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	b, ok := rl.buckets[prefix]
+	if !ok {
+		b = &prefixBuckets{updated: now, records: rl.cfg.RecordsBurst, bytes: rl.cfg.BytesBurst}
+		rl.buckets[prefix] = b
+	}
+
+	// Пополняем запасы пропорционально прошедшему времени
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.records = minFloat(rl.cfg.RecordsBurst, b.records+elapsed*rl.cfg.Records)
+		b.bytes = minFloat(rl.cfg.BytesBurst, b.bytes+elapsed*rl.cfg.Bytes)
+		b.updated = now
+	}
+
+	recordsOK := rl.cfg.Records <= 0 || b.records >= 1
+	bytesOK := rl.cfg.Bytes <= 0 || b.bytes >= float64(size)
+	if !recordsOK || !bytesOK {
+		b.dropped++
+		b.pending++
+		return false, nil
+	}
+
+	if rl.cfg.Records > 0 {
+		b.records--
+	}
+	if rl.cfg.Bytes > 0 {
+		b.bytes -= float64(size)
+	}
+
+	if b.pending > 0 {
+		summary := &rateLimitSummary{prefix: prefix, dropped: b.pending}
+		b.pending = 0
+		return true, summary
+	}
+	return true, nil
+}
+
+// drain возвращает сводки по всем префиксам с еще не отмеченными отброшенными записями
+func (rl *RateLimiter) drain() []rateLimitSummary {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	var summaries []rateLimitSummary
+	for prefix, b := range rl.buckets {
+		if b.pending > 0 {
+			summaries = append(summaries, rateLimitSummary{prefix: prefix, dropped: b.pending})
+			b.pending = 0
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].prefix < summaries[j].prefix })
+	return summaries
+}
+
+// minFloat возвращает меньшее из двух чисел
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// rateLimit применяет ограничитель логгера к закодированной записи
+func (l *Logger) rateLimit(record *Record, size int) bool {
+	if l.limiter == nil {
+		return true
+	}
+	allowed, summary := l.limiter.allow(record.Prefix, record.Level, size)
+	if summary != nil {
+		l.writeRateLimitSummary(*summary)
+	}
+	return allowed
+}
+
+// writeRateLimitSummary выводит запись об отброшенных ограничителем записях префикса
+func (l *Logger) writeRateLimitSummary(s rateLimitSummary) {
+	record := l.newRecord(WarningLevel, fmt.Sprintf("rate limit exceeded: dropped %d records", s.dropped), map[string]interface{}{
+		DroppedKey: s.dropped,
+	})
+	record.Prefix = s.prefix
+	l.write(record)
+}
+
+// flushRateLimiter выводит сводки по всем префиксам с отброшенными записями
+func (l *Logger) flushRateLimiter() {
+	if l.limiter == nil {
+		return
+	}
+	for _, s := range l.limiter.drain() {
+		l.writeRateLimitSummary(s)
+	}
+}
+
+// WithRateLimiter создает новый логгер с ограничением потока записей по префиксам.
+// Ограничитель разделяется между логгером и всеми его потомками
+func (l *Logger) WithRateLimiter(rl *RateLimiter) *Logger {
+	newLogger := l.clone()
+	newLogger.limiter = rl
+	return newLogger
+}
+
+// RateLimiter возвращает ограничитель логгера или nil, если ограничения не настроены
+func (l *Logger) RateLimiter() *RateLimiter {
+	return l.limiter
+}
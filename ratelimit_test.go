@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRateLimiter(cfg RateLimitConfig) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC)}
+	rl := NewRateLimiter(cfg)
+	rl.now = clock.Now
+	return rl, clock
+}
+
+func TestRateLimiterRecords(t *testing.T) {
+	rl, clock := newTestRateLimiter(RateLimitConfig{Records: 2})
+	l := New().WithRateLimiter(rl)
+	noisy := l.WithPrefix("NOISY")
+	quiet := l.WithPrefix("QUIET")
+
+	for i := 0; i < 5; i++ {
+		noisy.Infof("event %d", i)
+	}
+	quiet.Info("still logged")
+
+	assert.Len(t, noisy.GetMessages(), 2)
+	assert.Len(t, quiet.GetMessages(), 1)
+	assert.Equal(t, uint64(3), rl.Dropped())
+	assert.Equal(t, map[string]uint64{"NOISY": 3}, rl.DroppedByPrefix())
+
+	// После пополнения запаса выводится отчет и сама запись
+	clock.Advance(time.Second)
+	noisy.Info("after refill")
+
+	messages := noisy.GetMessages()
+	require.Len(t, messages, 4)
+	assert.Contains(t, messages[2], "[WARNING] [NOISY] rate limit exceeded: dropped 3 records [dropped=3]")
+	assert.Contains(t, messages[3], "[INFO] [NOISY] after refill")
+}
+
+func TestRateLimiterBytes(t *testing.T) {
+	rl, _ := newTestRateLimiter(RateLimitConfig{Bytes: 100})
+	l := New().WithRateLimiter(rl)
+
+	l.Info(strings.Repeat("x", 40))
+	l.Info(strings.Repeat("y", 40))
+	l.Info("short")
+
+	messages := l.GetMessages()
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], strings.Repeat("x", 40))
+	assert.Equal(t, uint64(2), rl.Dropped())
+}
+
+func TestRateLimiterExemptErrors(t *testing.T) {
+	rl, _ := newTestRateLimiter(RateLimitConfig{Records: 1, ExemptErrors: true})
+	l := New().WithRateLimiter(rl)
+
+	l.Info("first")
+	l.Info("second")
+	l.Error("error 1")
+	l.Error("error 2")
+
+	assert.Equal(t, 1, countContaining(l.GetMessages(), "[INFO]"))
+	assert.Equal(t, 2, countContaining(l.GetMessages(), "[ERROR]"))
+}
+
+func TestRateLimiterReportOnClose(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.RateLimit = &RateLimitConfig{Records: 1}
+	require.NoError(t, cfg.Validate())
+
+	l := New().WithConfig(cfg).(*Logger)
+	require.NotNil(t, l.RateLimiter())
+	l.Info("a")
+	l.Info("b")
+	l.Info("c")
+	require.NoError(t, l.Close())
+
+	messages := l.GetMessages()
+	require.Len(t, messages, 2)
+	assert.Contains(t, messages[1], "rate limit exceeded: dropped 2 records")
+
+	cfg.RateLimit = &RateLimitConfig{Bytes: -1}
+	require.Error(t, cfg.Validate())
+}
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+)
+
+// Record представляет одну полностью сформированную запись лога
+// Записи создаются логгером и передаются кодировщикам для вывода
+type Record struct {
+	Time    time.Time
+	Fields  map[string]interface{}
+	Caller  *Caller
+	Prefix  string
+	Message string
+	// Stack трассировка стека в многострочном виде, пустая если не собиралась
+	Stack string
+	Level Level
+}
+
+// newRecord создает запись с текущим временем и префиксом логгера
+func (l *Logger) newRecord(level Level, msg string, fields map[string]interface{}) *Record {
+	return &Record{
+		Time:    time.Now(),
+		Level:   level,
+		Prefix:  l.prefix,
+		Message: msg,
+		Fields:  fields,
+	}
+}
+
+// fieldPair пара ключ-значение для форматов без вложенных структур
+type fieldPair struct {
+	Key   string
+	Value string
+}
+
+// flatFields возвращает место вызова и поля записи плоским списком пар.
+// Пользовательские поля упорядочены по ключу, ошибки развернуты как в текстовом формате
+func flatFields(r *Record) []fieldPair {
+	var pairs []fieldPair
+	if r.Caller != nil {
+		pairs = append(pairs, fieldPair{Key: CallerKey, Value: r.Caller.String()})
+		if r.Caller.Function != "" {
+			pairs = append(pairs, fieldPair{Key: FunctionKey, Value: r.Caller.Function})
+		}
+	}
+	for _, k := range sortedKeys(r.Fields) {
+		if err, ok := r.Fields[k].(error); ok && err != nil {
+			pairs = append(pairs, describeError(err, 0).flatten(k)...)
+			continue
+		}
+		pairs = append(pairs, fieldPair{Key: k, Value: fmt.Sprint(r.Fields[k])})
+	}
+	return pairs
+}
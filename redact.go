@@ -0,0 +1,296 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// RedactMode определяет способ маскирования чувствительных значений
+type RedactMode int
+
+// Режимы маскирования
+const (
+	// RedactFull полностью заменяет значение на RedactedValue
+	RedactFull RedactMode = iota
+	// RedactPartial оставляет видимыми последние символы значения
+	RedactPartial
+	// RedactHash заменяет значение усеченным SHA-256 хешем,
+	// что позволяет сопоставлять записи без раскрытия значения
+	RedactHash
+)
+
+// RedactedValue значение, подставляемое вместо полностью скрытых данных
+const RedactedValue = "[REDACTED]"
+
+// partialVisible количество символов, остающихся видимыми в режиме RedactPartial
+const partialVisible = 4
+
+// DefaultRedactKeys имена полей, скрываемых по умолчанию
+var DefaultRedactKeys = []string{
+	"password", "passwd", "secret", "token", "access_token", "refresh_token",
+	"api_key", "apikey", "authorization", "cookie", "private_key",
+}
+
+// Встроенные шаблоны чувствительных значений
+var (
+	// PatternCreditCard номера банковских карт из 13-19 цифр, возможно разделенных пробелами или дефисами.
+	// Redactor маскирует только совпадения с корректной контрольной суммой по алгоритму Луна
+	PatternCreditCard = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	// PatternJWT JSON Web Token из трех частей в кодировке base64url
+	PatternJWT = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	// PatternEmail адреса электронной почты
+	PatternEmail = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// builtinPatterns сопоставляет имена встроенных шаблонов для использования в конфигурации
+var builtinPatterns = map[string]*regexp.Regexp{
+	"credit_card": PatternCreditCard,
+	"jwt":         PatternJWT,
+	"email":       PatternEmail,
+}
+
+// patternChecks дополнительные проверки совпадений встроенных шаблонов.
+// Совпадение, не прошедшее проверку, не маскируется
+var patternChecks = map[*regexp.Regexp]func(string) bool{
+	PatternCreditCard: luhnValid,
+}
+
+// luhnValid проверяет контрольную сумму номера карты по алгоритму Луна,
+// чтобы не маскировать временные метки и идентификаторы той же длины
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// RedactConfig описывает правила маскирования чувствительных данных
+type RedactConfig struct {
+	// Keys имена полей, значения которых скрываются целиком (без учета регистра).
+	// Если список пуст, используется DefaultRedactKeys
+	Keys []string
+	// Patterns регулярные выражения для поиска чувствительных значений в строках.
+	// Допускаются имена встроенных шаблонов: "credit_card", "jwt", "email"
+	Patterns []string
+	// Mode режим маскирования: "full" (по умолчанию), "partial" или "hash"
+	Mode string
+	// Message применяет шаблоны также к тексту сообщения
+	Message bool
+}
+
+// Redactor маскирует чувствительные данные в записях до их кодирования
+type Redactor struct {
+	keys     map[string]bool
+	patterns []*regexp.Regexp
+	mode     RedactMode
+	message  bool
+}
+
+// NewRedactor создает Redactor по конфигурации
+func NewRedactor(cfg RedactConfig) (*Redactor, error) {
+	r := &Redactor{
+		keys:    make(map[string]bool),
+		message: cfg.Message,
+	}
+
+	keys := cfg.Keys
+	if len(keys) == 0 {
+		keys = DefaultRedactKeys
+	}
+	for _, k := range keys {
+		r.keys[strings.ToLower(k)] = true
+	}
+
+	for _, p := range cfg.Patterns {
+		if re, ok := builtinPatterns[strings.ToLower(p)]; ok {
+			r.patterns = append(r.patterns, re)
+			continue
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, &ConfigError{Err: err, Reason: "некорректный шаблон маскирования " + p}
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	mode, err := parseRedactMode(cfg.Mode)
+	if err != nil {
+		return nil, err
+	}
+	r.mode = mode
+
+	return r, nil
+}
+
+// parseRedactMode преобразует название режима маскирования в RedactMode
+func parseRedactMode(mode string) (RedactMode, error) {
+	switch strings.ToLower(mode) {
+	case "", "full":
+		return RedactFull, nil
+	case "partial":
+		return RedactPartial, nil
+	case "hash":
+		return RedactHash, nil
+	default:
+		return RedactFull, &ConfigError{
+			Err:    fmt.Errorf("неизвестный режим маскирования: %s", mode),
+			Reason: "некорректный режим маскирования",
+		}
+	}
+}
+
+// Redact маскирует поля и, при необходимости, сообщение записи.
+// Поля логгера не изменяются: запись получает собственную копию
+func (r *Redactor) Redact(rec *Record) {
+	if r.message {
+		rec.Message = r.redactString(rec.Message)
+	}
+	if len(rec.Fields) > 0 {
+		rec.Fields = r.redactMap(rec.Fields)
+	}
+}
+
+// redactMap возвращает копию map с замаскированными значениями
+func (r *Redactor) redactMap(fields map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if r.keys[strings.ToLower(k)] {
+			redacted[k] = r.mask(fmt.Sprint(v))
+			continue
+		}
+		redacted[k] = r.redactValue(v)
+	}
+	return redacted
+}
+
+// redactValue обрабатывает значение поля, включая вложенные структуры
+func (r *Redactor) redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return r.redactString(val)
+	case map[string]interface{}:
+		return r.redactMap(val)
+	case Fields:
+		return Fields(r.redactMap(val))
+	case map[string]string:
+		redacted := make(map[string]string, len(val))
+		for k, s := range val {
+			if r.keys[strings.ToLower(k)] {
+				redacted[k] = r.mask(s)
+			} else {
+				redacted[k] = r.redactString(s)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(val))
+		for i, item := range val {
+			redacted[i] = r.redactValue(item)
+		}
+		return redacted
+	case []string:
+		redacted := make([]string, len(val))
+		for i, s := range val {
+			redacted[i] = r.redactString(s)
+		}
+		return redacted
+	case error:
+		// Ошибка сохраняется как есть, если ни ее цепочка, ни ее поля
+		// не содержат чувствительных данных
+		info := describeError(val, 0)
+		if redacted, changed := r.redactError(info); changed {
+			return &redactedError{err: val, info: redacted}
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// redactError маскирует сообщения и поля ошибки и всех ее причин.
+// Тип и структура цепочки сохраняются
+func (r *Redactor) redactError(info errorInfo) (errorInfo, bool) {
+	redacted := info
+	redacted.Message = r.redactString(info.Message)
+	changed := redacted.Message != info.Message
+	if len(info.Fields) > 0 {
+		redacted.Fields = r.redactMap(info.Fields)
+		changed = changed || !reflect.DeepEqual(redacted.Fields, info.Fields)
+	}
+	if len(info.Causes) > 0 {
+		redacted.Causes = make([]errorInfo, len(info.Causes))
+		for i, cause := range info.Causes {
+			var causeChanged bool
+			redacted.Causes[i], causeChanged = r.redactError(cause)
+			changed = changed || causeChanged
+		}
+	}
+	return redacted, changed
+}
+
+// redactedError ошибка с замаскированным описанием. Кодировщики выводят
+// замаскированное описание, а Unwrap возвращает исходную ошибку,
+// поэтому errors.Is и errors.As продолжают работать
+type redactedError struct {
+	err  error
+	info errorInfo
+}
+
+func (e *redactedError) Error() string { return e.info.Message }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactString маскирует фрагменты строки, совпадающие с шаблонами
+func (r *Redactor) redactString(s string) string {
+	for _, re := range r.patterns {
+		check := patternChecks[re]
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			if check != nil && !check(match) {
+				return match
+			}
+			return r.mask(match)
+		})
+	}
+	return s
+}
+
+// mask маскирует значение в соответствии с режимом
+func (r *Redactor) mask(s string) string {
+	switch r.mode {
+	case RedactPartial:
+		runes := []rune(s)
+		if len(runes) <= partialVisible*2 {
+			return RedactedValue
+		}
+		return strings.Repeat("*", len(runes)-partialVisible) + string(runes[len(runes)-partialVisible:])
+	case RedactHash:
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:])[:12]
+	default:
+		return RedactedValue
+	}
+}
+
+// WithRedactor создает новый логгер, маскирующий чувствительные данные перед записью
+func (l *Logger) WithRedactor(r *Redactor) *Logger {
+	newLogger := l.clone()
+	newLogger.redactor = r
+	return newLogger
+}
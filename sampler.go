@@ -0,0 +1,228 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Значения по умолчанию для сэмплирования
+const (
+	DefaultSamplingInterval = time.Second
+	DefaultSamplingInitial  = 100
+)
+
+// SamplingConfig описывает сэмплирование часто повторяющихся записей: в течение
+// каждого интервала для пары (уровень, шаблон сообщения) пропускаются первые Initial
+// записей, а затем каждая Thereafter-я
+type SamplingConfig struct {
+	// Interval длительность окна подсчета, по умолчанию DefaultSamplingInterval
+	Interval time.Duration
+	// Initial количество записей, пропускаемых без ограничений. Если не заданы
+	// ни Initial, ни Thereafter, используется DefaultSamplingInitial
+	Initial int
+	// Thereafter после Initial пропускается каждая Thereafter-я запись; 0 отбрасывает все остальные
+	Thereafter int
+}
+
+// Sampler ограничивает поток одинаковых записей. Записи уровня FATAL не сэмплируются.
+// Сводки об отброшенных записях выводятся периодически, по окончании окна шаблона
+type Sampler struct {
+	dropped  uint64 // первое поле для выравнивания атомарного счетчика на 32-битных платформах
+	now      func() time.Time
+	counters map[sampleKey]*sampleCounter
+	owner    *Logger
+	timer    *time.Timer
+	mu       sync.Mutex
+	cfg      SamplingConfig
+}
+
+// sampleKey ключ счетчика: уровень и полный шаблон сообщения
+type sampleKey struct {
+	template string
+	level    Level
+}
+
+// sampleCounter счетчик записей одного шаблона в текущем окне
+type sampleCounter struct {
+	resetAt time.Time
+	count   int
+	dropped int
+}
+
+// sampledSummary сводка об отброшенных записях одного шаблона
+type sampledSummary struct {
+	template string
+	dropped  int
+	level    Level
+}
+
+// NewSampler создает сэмплер по конфигурации
+func NewSampler(cfg SamplingConfig) *Sampler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultSamplingInterval
+	}
+	if cfg.Initial == 0 && cfg.Thereafter == 0 {
+		cfg.Initial = DefaultSamplingInitial
+	}
+	return &Sampler{cfg: cfg, now: time.Now, counters: make(map[sampleKey]*sampleCounter)}
+}
+
+// Dropped возвращает общее количество отброшенных сэмплером записей
+func (s *Sampler) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// allow решает, пропустить ли запись. Если для шаблона закончилось окно, в котором
+// были отброшены записи, возвращается сводка для вывода перед текущей записью.
+// Логгер l используется для вывода периодических сводок
+func (s *Sampler) allow(l *Logger, level Level, template string) (bool, *sampledSummary) {
+	if level < DebugLevel || level >= FatalLevel {
+		return true, nil
+	}
+
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.owner = l
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.cfg.Interval, s.tick)
+	}
+
+	key := sampleKey{level: level, template: template}
+	c := s.counters[key]
+	if c == nil {
+		c = &sampleCounter{}
+		s.counters[key] = c
+	}
+	var summary *sampledSummary
+	if now.After(c.resetAt) {
+		if c.dropped > 0 {
+			summary = &sampledSummary{level: level, template: template, dropped: c.dropped}
+		}
+		c.resetAt = now.Add(s.cfg.Interval)
+		c.count = 0
+		c.dropped = 0
+	}
+	c.count++
+
+	if c.count <= s.cfg.Initial {
+		return true, summary
+	}
+	if s.cfg.Thereafter > 0 && (c.count-s.cfg.Initial)%s.cfg.Thereafter == 0 {
+		return true, summary
+	}
+
+	c.dropped++
+	atomic.AddUint64(&s.dropped, 1)
+	return false, summary
+}
+
+// tick выводит сводки по шаблонам с закончившимся окном и удаляет их счетчики,
+// чтобы память сэмплера не росла с числом различных сообщений
+func (s *Sampler) tick() {
+	now := s.now()
+	s.mu.Lock()
+	var summaries []sampledSummary
+	for key, c := range s.counters {
+		if !now.After(c.resetAt) {
+			continue
+		}
+		if c.dropped > 0 {
+			summaries = append(summaries, sampledSummary{level: key.level, template: key.template, dropped: c.dropped})
+		}
+		delete(s.counters, key)
+	}
+	s.timer = nil
+	if len(s.counters) > 0 {
+		s.timer = time.AfterFunc(s.cfg.Interval, s.tick)
+	}
+	owner := s.owner
+	s.mu.Unlock()
+
+	sortSummaries(summaries)
+	for _, summary := range summaries {
+		owner.writeSampledSummary(summary)
+	}
+}
+
+// drain возвращает сводки по всем шаблонам с отброшенными записями и сбрасывает их.
+// Периодический вывод останавливается до следующей записи
+func (s *Sampler) drain() []sampledSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	var summaries []sampledSummary
+	for key, c := range s.counters {
+		if c.dropped > 0 {
+			summaries = append(summaries, sampledSummary{level: key.level, template: key.template, dropped: c.dropped})
+			c.dropped = 0
+		}
+	}
+	sortSummaries(summaries)
+	return summaries
+}
+
+// sortSummaries упорядочивает сводки по уровню и шаблону для стабильного вывода
+func sortSummaries(summaries []sampledSummary) {
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].level != summaries[j].level {
+			return summaries[i].level < summaries[j].level
+		}
+		return summaries[i].template < summaries[j].template
+	})
+}
+
+// sample применяет сэмплер логгера к записи и выводит сводку об отброшенных записях
+func (l *Logger) sample(level Level, template string) bool {
+	if l.sampler == nil {
+		return true
+	}
+	allowed, summary := l.sampler.allow(l, level, template)
+	if summary != nil {
+		l.writeSampledSummary(*summary)
+	}
+	return allowed
+}
+
+// writeSampledSummary выводит запись вида "dropped 1234 similar messages"
+func (l *Logger) writeSampledSummary(s sampledSummary) {
+	record := l.newRecord(s.level, fmt.Sprintf("dropped %d similar messages", s.dropped), map[string]interface{}{
+		SampledKey: s.template,
+	})
+	// Шаблон содержит исходный текст сообщения, поэтому маскируется как и сама запись
+	if l.redactor != nil {
+		l.redactor.Redact(record)
+	}
+	l.write(record)
+}
+
+// flushSampler выводит сводки по всем отброшенным, но еще не отмеченным записям
+func (l *Logger) flushSampler() {
+	if l.sampler == nil {
+		return
+	}
+	for _, s := range l.sampler.drain() {
+		l.writeSampledSummary(s)
+	}
+}
+
+// WithSampler создает новый логгер, сэмплирующий записи. Сэмплер разделяется
+// между логгером и всеми его потомками
+func (l *Logger) WithSampler(s *Sampler) *Logger {
+	newLogger := l.clone()
+	newLogger.sampler = s
+	return newLogger
+}
+
+// Sampler возвращает сэмплер логгера или nil, если сэмплирование не настроено
+func (l *Logger) Sampler() *Sampler {
+	return l.sampler
+}
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Sink дополнительное назначение вывода записей: файл, сетевой коллектор и т.п.
+// Sink получает запись до кодирования и сам выбирает формат. Реализации должны
+// быть потокобезопасными, а Close должен допускать повторный вызов
+type Sink interface {
+	Write(r *Record) error
+	Close() error
+}
+
+// FileSink записывает закодированные записи в файл, по одной на строку
+type FileSink struct {
+	encoder Encoder
+	file    *os.File
+	mu      sync.Mutex
+}
+
+// NewFileSink открывает файл для дозаписи, создавая каталог при необходимости.
+// Если encoder равен nil, используется TextEncoder
+func NewFileSink(filename string, encoder Encoder) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0750); err != nil {
+		return nil, &WriteError{Message: "не удалось создать каталог для " + filename, Cause: err}
+	}
+
+	f, err := os.OpenFile(filepath.Clean(filename), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, &WriteError{Message: "не удалось открыть файл " + filename, Cause: err}
+	}
+
+	if encoder == nil {
+		encoder = TextEncoder{}
+	}
+	return &FileSink{file: f, encoder: encoder}, nil
+}
+
+// Write реализует интерфейс Sink
+func (s *FileSink) Write(r *Record) error {
+	line := s.encoder.Encode(r) + "\n"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return &WriteError{Message: "файл уже закрыт"}
+	}
+	_, err := s.file.WriteString(line)
+	return err
+}
+
+// Close реализует интерфейс Sink
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	if err := s.file.Sync(); err != nil {
+		_ = s.file.Close()
+		s.file = nil
+		return err
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// FileSinkConfig декларативное описание дополнительного файла:
+//
+//	FileSinks: []logger.FileSinkConfig{{
+//		Path:    "/var/log/app/payments.log",
+//		Filters: []logger.FilterRule{{Action: "include", Prefix: "PAYMENTS"}},
+//	}}
+type FileSinkConfig struct {
+	// Path путь к файлу
+	Path string
+	// Format формат записей: "text" (по умолчанию) или "json"
+	Format string
+	// Filters правила отбора записей для этого файла
+	Filters []FilterRule
+}
+
+// validate проверяет описание файла без его открытия
+func (c FileSinkConfig) validate() error {
+	if c.Path == "" {
+		return fmt.Errorf("не указан путь к дополнительному файлу лога")
+	}
+	switch strings.ToLower(c.Format) {
+	case "", FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("некорректный формат вывода: %s", c.Format)
+	}
+	_, err := NewFilter(c.Filters)
+	return err
+}
+
+// newFileSinkFromConfig открывает файл по описанию и оборачивает его фильтром.
+// Текстовый формат использует политику управляющих символов логгера
+func newFileSinkFromConfig(c FileSinkConfig, timeFormat string, control ControlPolicy) (Sink, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	var encoder Encoder = TextEncoder{TimeFormat: timeFormat, Control: control}
+	if strings.ToLower(c.Format) == FormatJSON {
+		encoder = JSONEncoder{TimeFormat: timeFormat}
+	}
+	sink, err := NewFileSink(c.Path, encoder)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Filters) == 0 {
+		return sink, nil
+	}
+	filter, err := NewFilter(c.Filters)
+	if err != nil {
+		_ = sink.Close()
+		return nil, err
+	}
+	return NewFilteredSink(sink, filter), nil
+}
+
+// writeSinks передает запись всем дополнительным назначениям логгера
+func (l *Logger) writeSinks(r *Record) {
+	for _, s := range l.sinks {
+		if err := s.Write(r); err != nil {
+			l.handleWriteError(&WriteError{Message: "не удалось передать запись в назначение", Cause: err})
+		}
+	}
+}
+
+// closeSinks закрывает дополнительные назначения и возвращает последнюю ошибку
+func (l *Logger) closeSinks() error {
+	var closeErr error
+	for _, s := range l.sinks {
+		if err := s.Close(); err != nil {
+			closeErr = err
+		}
+	}
+	return closeErr
+}
+
+// WithSink создает новый логгер, дополнительно передающий записи в sink.
+// Назначения наследуются дочерними логгерами и закрываются вызовом Close
+func (l *Logger) WithSink(s Sink) *Logger {
+	newLogger := l.clone()
+	newLogger.sinks = append(append([]Sink{}, l.sinks...), s)
+	return newLogger
+}
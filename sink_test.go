@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSinkWritesRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "sink.log")
+	sink, err := NewFileSink(path, JSONEncoder{})
+	require.NoError(t, err)
+
+	l := New().WithSink(sink)
+	l.WithPrefix("API").Info("hello")
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "API", entry[PrefixKey])
+	assert.Equal(t, "hello", entry[MessageKey])
+}
+
+func TestFileSinkClose(t *testing.T) {
+	sink, err := NewFileSink(filepath.Join(t.TempDir(), "sink.log"), nil)
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Close())
+	require.NoError(t, sink.Close())
+
+	err = sink.Write(newRecordForTest(InfoLevel, "late"))
+	var writeErr *WriteError
+	assert.ErrorAs(t, err, &writeErr)
+}
+
+func TestFileSinkConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.FileSinks = []FileSinkConfig{{
+		Path:    filepath.Join(dir, "payments.log"),
+		Filters: []FilterRule{{Action: FilterInclude, Prefix: "PAYMENTS"}},
+	}}
+	require.NoError(t, cfg.Validate())
+
+	l := New().WithConfig(cfg)
+	l.WithPrefix("PAYMENTS").Info("charged")
+	l.WithPrefix("API").Info("request")
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(filepath.Join(dir, "payments.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "charged")
+	assert.NotContains(t, string(data), "request")
+
+	cfg.FileSinks = []FileSinkConfig{{Path: filepath.Join(dir, "x.log"), Format: "xml"}}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestFileSinkConfigControlPolicy(t *testing.T) {
+	dir := t.TempDir()
+	for _, policy := range []string{"", "strip"} {
+		path := filepath.Join(dir, "policy-"+policy+".log")
+		l := New().WithConfig(&Config{Level: "info", ControlChars: policy, FileSinks: []FileSinkConfig{{Path: path}}})
+		l.Info("user\n2024-01-01 00:00:00 [ERROR] forged")
+		require.NoError(t, l.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(data), "\n"), policy)
+		if policy == "strip" {
+			assert.Contains(t, string(data), "user2024-01-01 00:00:00 [ERROR] forged")
+		} else {
+			assert.Contains(t, string(data), `user\n2024-01-01 00:00:00 [ERROR] forged`)
+		}
+	}
+}
+
+// newRecordForTest создает запись без логгера
+func newRecordForTest(level Level, msg string) *Record {
+	return &Record{Level: level, Message: msg}
+}
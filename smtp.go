@@ -0,0 +1,330 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Параметры назначения SMTP по умолчанию
+const (
+	DefaultSMTPWindow     = time.Minute
+	DefaultSMTPMaxRecords = 100
+	DefaultSMTPSubject    = "Log alerts"
+	DefaultSMTPTimeout    = 10 * time.Second
+)
+
+// SMTPConfig параметры назначения оповещений по электронной почте
+type SMTPConfig struct {
+	// TLS параметры STARTTLS, по умолчанию проверка сертификата для имени сервера
+	TLS *tls.Config
+	// Encoder кодировщик записей в тексте письма, по умолчанию TextEncoder
+	Encoder Encoder
+	// Levels уровни, по которым отправляются письма, по умолчанию ERROR и FATAL
+	Levels []Level
+	// To адреса получателей
+	To []string
+	// Address адрес SMTP-сервера в виде host:port
+	Address string
+	// Username и Password данные аутентификации PLAIN; пустой Username отключает аутентификацию
+	Username string
+	Password string
+	// From адрес отправителя
+	From string
+	// Subject тема письма, к которой добавляются уровень и количество записей,
+	// по умолчанию DefaultSMTPSubject
+	Subject string
+	// StartTLS требует шифрования соединения командой STARTTLS
+	StartTLS bool
+	// Window время накопления записей для одного письма, по умолчанию DefaultSMTPWindow
+	Window time.Duration
+	// MaxRecords максимальное количество записей в письме, по умолчанию DefaultSMTPMaxRecords.
+	// Остальные записи окна только учитываются в сводке
+	MaxRecords int
+	// Timeout таймаут SMTP-сессии, по умолчанию DefaultSMTPTimeout
+	Timeout time.Duration
+}
+
+// SMTPStats счетчики назначения SMTP
+type SMTPStats struct {
+	SentMessages   uint64
+	FailedMessages uint64
+	Records        uint64
+}
+
+// SMTPSink отправляет письма со сводкой записей выбранных уровней, накопленных
+// за окно. Запись уровня FATAL отправляется вместе с накопленными синхронно
+// в вызове Write, то есть внутри Fatal до завершения программы
+type SMTPSink struct {
+	stats   SMTPStats // первое поле для выравнивания атомарных счетчиков на 32-битных платформах
+	cfg     SMTPConfig
+	levels  map[Level]bool
+	host    string
+	pending []*Record
+	omitted int
+	timer   *time.Timer
+	sending sync.WaitGroup
+	sendMu  sync.Mutex
+	mu      sync.Mutex
+	closed  bool
+}
+
+// smtpDigest письмо с записями одного окна
+type smtpDigest struct {
+	records []*Record
+	omitted int
+}
+
+// NewSMTPSink создает назначение SMTP
+func NewSMTPSink(cfg SMTPConfig) (*SMTPSink, error) {
+	if cfg.Address == "" {
+		return nil, &ConfigError{Err: fmt.Errorf("не указан адрес сервера"), Reason: "некорректное назначение SMTP"}
+	}
+	if cfg.From == "" || len(cfg.To) == 0 {
+		return nil, &ConfigError{Err: fmt.Errorf("не указаны отправитель или получатели"), Reason: "некорректное назначение SMTP"}
+	}
+	if _, _, err := net.SplitHostPort(cfg.Address); err != nil {
+		return nil, &ConfigError{Err: err, Reason: "некорректное назначение SMTP"}
+	}
+	if cfg.Encoder == nil {
+		cfg.Encoder = TextEncoder{}
+	}
+	if len(cfg.Levels) == 0 {
+		cfg.Levels = []Level{ErrorLevel, FatalLevel}
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = DefaultSMTPSubject
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultSMTPWindow
+	}
+	if cfg.MaxRecords <= 0 {
+		cfg.MaxRecords = DefaultSMTPMaxRecords
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultSMTPTimeout
+	}
+
+	s := &SMTPSink{cfg: cfg, levels: make(map[Level]bool, len(cfg.Levels))}
+	for _, level := range cfg.Levels {
+		s.levels[level] = true
+	}
+	s.host, _ = os.Hostname()
+	return s, nil
+}
+
+// Write реализует интерфейс Sink. Записи других уровней пропускаются
+func (s *SMTPSink) Write(r *Record) error {
+	if !s.levels[r.Level] {
+		return nil
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return &WriteError{Message: "назначение SMTP закрыто"}
+	}
+	atomic.AddUint64(&s.stats.Records, 1)
+	if len(s.pending) < s.cfg.MaxRecords {
+		s.pending = append(s.pending, r)
+	} else {
+		s.omitted++
+	}
+
+	if r.Level != FatalLevel {
+		if s.timer == nil {
+			s.timer = time.AfterFunc(s.cfg.Window, s.tick)
+		}
+		s.mu.Unlock()
+		return nil
+	}
+
+	// Программа завершится сразу после записи, поэтому письмо отправляется
+	// синхронно, не дожидаясь окончания окна
+	digest := s.cut()
+	s.mu.Unlock()
+	return s.send(digest)
+}
+
+// tick отправляет письмо по истечении окна
+func (s *SMTPSink) tick() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	digest := s.cut()
+	s.sending.Add(1)
+	s.mu.Unlock()
+
+	defer s.sending.Done()
+	if err := s.send(digest); err != nil {
+		reportSinkError("smtp", err)
+	}
+}
+
+// cut забирает накопленные записи. Вызывается под блокировкой
+func (s *SMTPSink) cut() smtpDigest {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	digest := smtpDigest{records: s.pending, omitted: s.omitted}
+	s.pending = nil
+	s.omitted = 0
+	return digest
+}
+
+// send отправляет письмо; пустая сводка пропускается
+func (s *SMTPSink) send(digest smtpDigest) error {
+	if len(digest.records) == 0 {
+		return nil
+	}
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	if err := s.deliver(s.message(digest)); err != nil {
+		atomic.AddUint64(&s.stats.FailedMessages, 1)
+		return &WriteError{Message: "не удалось отправить письмо", Cause: err}
+	}
+	atomic.AddUint64(&s.stats.SentMessages, 1)
+	return nil
+}
+
+// message формирует письмо с заголовками и телом в кодировке quoted-printable
+func (s *SMTPSink) message(digest smtpDigest) []byte {
+	maxLevel := DebugLevel
+	for _, r := range digest.records {
+		if r.Level > maxLevel {
+			maxLevel = r.Level
+		}
+	}
+	total := len(digest.records) + digest.omitted
+	subject := fmt.Sprintf("[%s] %s: %d records on %s", maxLevel, s.cfg.Subject, total, s.host)
+
+	var msg bytes.Buffer
+	msg.WriteString("From: " + s.cfg.From + "\r\n")
+	msg.WriteString("To: " + strings.Join(s.cfg.To, ", ") + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&msg)
+	fmt.Fprintf(qp, "%d records on %s\r\n\r\n", total, s.host)
+	for _, r := range digest.records {
+		fmt.Fprintf(qp, "%s\r\n", strings.ReplaceAll(s.cfg.Encoder.Encode(r), "\n", "\r\n"))
+	}
+	if digest.omitted > 0 {
+		fmt.Fprintf(qp, "\r\n... and %d more records\r\n", digest.omitted)
+	}
+	_ = qp.Close()
+	return msg.Bytes()
+}
+
+// deliver выполняет SMTP-сессию
+func (s *SMTPSink) deliver(msg []byte) error {
+	host, _, _ := net.SplitHostPort(s.cfg.Address)
+	conn, err := net.DialTimeout("tcp", s.cfg.Address, s.cfg.Timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(s.cfg.Timeout)); err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := s.startTLS(c, host); err != nil {
+		return err
+	}
+	if err := s.auth(c, host); err != nil {
+		return err
+	}
+
+	if err := c.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range s.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// startTLS переходит на TLS командой STARTTLS, если она включена
+func (s *SMTPSink) startTLS(c *smtp.Client, host string) error {
+	if !s.cfg.StartTLS {
+		return nil
+	}
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		return fmt.Errorf("сервер не поддерживает STARTTLS")
+	}
+	tlsConfig := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	if s.cfg.TLS != nil {
+		tlsConfig = s.cfg.TLS.Clone()
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = host
+		}
+	}
+	return c.StartTLS(tlsConfig)
+}
+
+// auth выполняет аутентификацию PLAIN, если указано имя пользователя
+func (s *SMTPSink) auth(c *smtp.Client, host string) error {
+	if s.cfg.Username == "" {
+		return nil
+	}
+	return c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, host))
+}
+
+// Stats возвращает счетчики назначения
+func (s *SMTPSink) Stats() SMTPStats {
+	return SMTPStats{
+		SentMessages:   atomic.LoadUint64(&s.stats.SentMessages),
+		FailedMessages: atomic.LoadUint64(&s.stats.FailedMessages),
+		Records:        atomic.LoadUint64(&s.stats.Records),
+	}
+}
+
+// Close реализует интерфейс Sink. Отправляет накопленные записи, не дожидаясь окончания окна
+func (s *SMTPSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	digest := s.cut()
+	s.mu.Unlock()
+
+	err := s.send(digest)
+	s.sending.Wait()
+	return err
+}
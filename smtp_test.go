@@ -0,0 +1,253 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpServer фейковый SMTP-сервер, сохраняющий принятые письма
+type smtpServer struct {
+	addr     string
+	tls      *tls.Config
+	mu       sync.Mutex
+	messages []*mail.Message
+	bodies   []string
+	rcpts    [][]string
+	auth     string
+	secure   bool
+}
+
+func newSMTPServer(t *testing.T, tlsConfig *tls.Config) *smtpServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	s := &smtpServer{addr: ln.Addr().String(), tls: tlsConfig}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP")
+	var rcpts []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		switch verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			if s.tls != nil {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, r = tlsConn, bufio.NewReader(tlsConn)
+			s.mu.Lock()
+			s.secure = true
+			s.mu.Unlock()
+		case "AUTH":
+			s.mu.Lock()
+			s.auth = cmd
+			s.mu.Unlock()
+			reply("235 authenticated")
+		case "MAIL":
+			rcpts = nil
+			reply("250 ok")
+		case "RCPT":
+			rcpts = append(rcpts, cmd)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(data.String()))
+			if err != nil {
+				reply("554 bad message")
+				continue
+			}
+			body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.bodies = append(s.bodies, string(body))
+			s.rcpts = append(s.rcpts, rcpts)
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown command")
+		}
+	}
+}
+
+// count возвращает количество принятых писем
+func (s *smtpServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.messages)
+}
+
+func TestSMTPSinkDigest(t *testing.T) {
+	server := newSMTPServer(t, nil)
+
+	sink, err := NewSMTPSink(SMTPConfig{
+		Address:    server.addr,
+		From:       "logger@example.com",
+		To:         []string{"oncall@example.com", "dev@example.com"},
+		Username:   "user",
+		Password:   "secret",
+		Window:     20 * time.Millisecond,
+		MaxRecords: 2,
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	l := New().WithSink(sink).WithPrefix("DB")
+	l.Info("not sent")
+	l.Error("connection lost")
+	l.WithFields(Fields{"attempt": 2}).Error("retry failed")
+	l.Error("over the limit")
+
+	// Письмо отправляется по истечении окна
+	require.Eventually(t, func() bool { return server.count() == 1 }, 5*time.Second, 5*time.Millisecond)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	msg := server.messages[0]
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(subject, "[ERROR] Log alerts: 3 records on "))
+	assert.Equal(t, "logger@example.com", msg.Header.Get("From"))
+	assert.Equal(t, "oncall@example.com, dev@example.com", msg.Header.Get("To"))
+	assert.Len(t, server.rcpts[0], 2)
+	assert.NotEmpty(t, server.auth)
+
+	body := server.bodies[0]
+	assert.Contains(t, body, "[ERROR] [DB] connection lost")
+	assert.Contains(t, body, "[ERROR] [DB] retry failed [attempt=2]")
+	assert.NotContains(t, body, "over the limit")
+	assert.Contains(t, body, "... and 1 more records")
+	assert.NotContains(t, body, "not sent")
+
+	stats := sink.Stats()
+	assert.Equal(t, uint64(1), stats.SentMessages)
+	assert.Equal(t, uint64(3), stats.Records)
+}
+
+func TestSMTPSinkFatalFlushesBeforeExit(t *testing.T) {
+	server := newSMTPServer(t, nil)
+
+	sink, err := NewSMTPSink(SMTPConfig{
+		Address: server.addr,
+		From:    "logger@example.com",
+		To:      []string{"oncall@example.com"},
+		Window:  time.Hour,
+	})
+	require.NoError(t, err)
+
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	delivered := -1
+	osExit = func(code int) { delivered = server.count() }
+
+	l := New().WithSink(sink)
+	l.Error("first")
+	l.Fatal("last words")
+
+	// Письмо с накопленными записями отправлено до завершения программы
+	require.Equal(t, 1, delivered)
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Contains(t, server.bodies[0], "[ERROR] first")
+	assert.Contains(t, server.bodies[0], "[FATAL] last words")
+	assert.Contains(t, server.messages[0].Header.Get("Subject"), "FATAL")
+}
+
+func TestSMTPSinkStartTLS(t *testing.T) {
+	// Сертификат тестового HTTPS-сервера используется для STARTTLS
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	server := newSMTPServer(t, srv.TLS)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	sink, err := NewSMTPSink(SMTPConfig{
+		Address:  server.addr,
+		From:     "logger@example.com",
+		To:       []string{"oncall@example.com"},
+		StartTLS: true,
+		TLS:      &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: ErrorLevel, Message: "secure"}))
+	require.NoError(t, sink.Close())
+	require.Equal(t, 1, server.count())
+	server.mu.Lock()
+	assert.True(t, server.secure)
+	server.mu.Unlock()
+
+	// Без поддержки STARTTLS на сервере письмо не отправляется
+	plain := newSMTPServer(t, nil)
+	sink, err = NewSMTPSink(SMTPConfig{Address: plain.addr, From: "a@example.com", To: []string{"b@example.com"}, StartTLS: true})
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: ErrorLevel, Message: "insecure"}))
+	assert.Error(t, sink.Close())
+	assert.Zero(t, plain.count())
+	assert.Equal(t, uint64(1), sink.Stats().FailedMessages)
+}
+
+func TestSMTPSinkConfig(t *testing.T) {
+	var cfgErr *ConfigError
+	_, err := NewSMTPSink(SMTPConfig{From: "a@example.com", To: []string{"b@example.com"}})
+	assert.ErrorAs(t, err, &cfgErr)
+	_, err = NewSMTPSink(SMTPConfig{Address: "localhost:25", To: []string{"b@example.com"}})
+	assert.ErrorAs(t, err, &cfgErr)
+	_, err = NewSMTPSink(SMTPConfig{Address: "localhost", From: "a@example.com", To: []string{"b@example.com"}})
+	assert.ErrorAs(t, err, &cfgErr)
+}
@@ -0,0 +1,77 @@
+package logger
+
+import "encoding/binary"
+
+// snappyEncode сжимает данные в блочном формате snappy, который требуется
+// для protobuf-запросов Loki и Prometheus. Используется простой поиск повторов
+// по хэшу 4-байтовых последовательностей: степень сжатия ниже, чем у эталонной
+// реализации, но формат полностью совместим
+func snappyEncode(src []byte) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(src)))
+	dst := append(make([]byte, 0, n+len(src)+len(src)/6+16), buf[:n]...)
+
+	const (
+		tableBits = 14
+		maxOffset = 1<<16 - 1
+	)
+	var table [1 << tableBits]int32
+
+	lit := 0
+	for i := 0; i+4 <= len(src); {
+		v := binary.LittleEndian.Uint32(src[i:])
+		h := (v * 0x1e35a7bd) >> (32 - tableBits)
+		cand := int(table[h]) - 1
+		table[h] = int32(i + 1)
+
+		if cand < 0 || i-cand > maxOffset || binary.LittleEndian.Uint32(src[cand:]) != v {
+			i++
+			continue
+		}
+
+		length := 4
+		for i+length < len(src) && src[cand+length] == src[i+length] {
+			length++
+		}
+		dst = snappyLiteral(dst, src[lit:i])
+		dst = snappyCopy(dst, i-cand, length)
+		i += length
+		lit = i
+	}
+	return snappyLiteral(dst, src[lit:])
+}
+
+// snappyLiteral добавляет элемент с несжатыми данными
+func snappyLiteral(dst, lit []byte) []byte {
+	if len(lit) == 0 {
+		return dst
+	}
+	n := len(lit) - 1
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n)<<2)
+	case n < 1<<8:
+		dst = append(dst, 60<<2, byte(n))
+	case n < 1<<16:
+		dst = append(dst, 61<<2, byte(n), byte(n>>8))
+	case n < 1<<24:
+		dst = append(dst, 62<<2, byte(n), byte(n>>8), byte(n>>16))
+	default:
+		dst = append(dst, 63<<2, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+	}
+	return append(dst, lit...)
+}
+
+// snappyCopy добавляет ссылки на повтор длиной length на расстоянии offset,
+// разбивая длинные повторы на элементы по 64 байта с двухбайтовым смещением
+func snappyCopy(dst []byte, offset, length int) []byte {
+	for length > 0 {
+		n := length
+		if n > 64 {
+			n = 64
+		}
+		dst = append(dst, byte(n-1)<<2|2, byte(offset), byte(offset>>8))
+		length -= n
+	}
+	return dst
+}
@@ -0,0 +1,344 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Параметры назначения Splunk HEC по умолчанию
+const (
+	DefaultSplunkSourceType  = "_json"
+	DefaultSplunkAckInterval = time.Second
+	DefaultSplunkAckTimeout  = time.Minute
+)
+
+// Пути HTTP Event Collector
+const (
+	splunkEventPath = "/services/collector/event"
+	splunkAckPath   = "/services/collector/ack"
+)
+
+// SplunkConfig параметры назначения Splunk HTTP Event Collector
+type SplunkConfig struct {
+	BatchConfig
+	RetryConfig
+	// Client HTTP-клиент, по умолчанию клиент с таймаутом DefaultHTTPTimeout
+	Client *http.Client
+	// Encoder кодировщик события, по умолчанию JSONEncoder. Результат, не являющийся
+	// JSON, передается строкой
+	Encoder Encoder
+	// URL адрес HEC, например https://splunk:8088
+	URL string
+	// Token токен HEC, передаваемый в заголовке Authorization: Splunk
+	Token string
+	// Host имя источника, по умолчанию os.Hostname
+	Host string
+	// Source, SourceType и Index метаданные событий; пустой Index означает
+	// индекс токена по умолчанию. SourceType по умолчанию DefaultSplunkSourceType
+	Source     string
+	SourceType string
+	Index      string
+	// Ack включает подтверждение индексации: пакет считается доставленным, только
+	// когда HEC подтвердит его через канал
+	Ack bool
+	// Channel идентификатор канала подтверждений (GUID), по умолчанию создается случайный
+	Channel string
+	// AckInterval пауза между опросами подтверждения, по умолчанию DefaultSplunkAckInterval
+	AckInterval time.Duration
+	// AckTimeout максимальное время ожидания подтверждения, по умолчанию DefaultSplunkAckTimeout
+	AckTimeout time.Duration
+}
+
+// SplunkSink отправляет записи в Splunk HTTP Event Collector пакетами событий.
+// При включенных подтверждениях пакеты отправляются без ожидания, а подтверждения
+// всех неподтвержденных пакетов запрашиваются в фоне одним запросом
+type SplunkSink struct {
+	*batchSender
+	cfg     SplunkConfig
+	headers map[string]string
+
+	mu sync.Mutex
+	// pending неподтвержденные пакеты по ackId
+	pending map[int64]splunkPending
+	closing bool
+	// wake будит опрос подтверждений после отправки пакета или при закрытии
+	wake    chan struct{}
+	abort   chan struct{}
+	aborted sync.Once
+	done    chan struct{}
+}
+
+// splunkPending пакет, ожидающий подтверждения индексации
+type splunkPending struct {
+	deadline time.Time
+	records  int
+}
+
+// splunkResponse ответ HEC на отправку событий
+type splunkResponse struct {
+	AckID *int64 `json:"ackId"`
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+}
+
+// NewSplunkSink создает назначение Splunk HEC
+func NewSplunkSink(cfg SplunkConfig) (*SplunkSink, error) {
+	if cfg.URL == "" {
+		return nil, &ConfigError{Err: fmt.Errorf("не указан URL"), Reason: "некорректное назначение Splunk"}
+	}
+	if cfg.Token == "" {
+		return nil, &ConfigError{Err: fmt.Errorf("не указан токен"), Reason: "некорректное назначение Splunk"}
+	}
+	if cfg.Encoder == nil {
+		cfg.Encoder = JSONEncoder{}
+	}
+	if cfg.Host == "" {
+		cfg.Host, _ = os.Hostname()
+	}
+	if cfg.SourceType == "" {
+		cfg.SourceType = DefaultSplunkSourceType
+	}
+	if cfg.AckInterval <= 0 {
+		cfg.AckInterval = DefaultSplunkAckInterval
+	}
+	if cfg.AckTimeout <= 0 {
+		cfg.AckTimeout = DefaultSplunkAckTimeout
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+
+	headers := map[string]string{"Authorization": "Splunk " + cfg.Token}
+	if cfg.Ack {
+		if cfg.Channel == "" {
+			channel, err := newSplunkChannel()
+			if err != nil {
+				return nil, err
+			}
+			cfg.Channel = channel
+		}
+		headers["X-Splunk-Request-Channel"] = cfg.Channel
+	}
+
+	s := &SplunkSink{cfg: cfg, headers: headers}
+	s.batchSender = newBatchSender("splunk", cfg.BatchConfig, cfg.Client, cfg.RetryConfig, s.request)
+	if cfg.Ack {
+		s.pending = make(map[int64]splunkPending)
+		s.wake = make(chan struct{}, 1)
+		s.abort = make(chan struct{})
+		s.done = make(chan struct{})
+		s.response = s.trackAck
+		go s.pollAcks()
+	}
+	return s, nil
+}
+
+// Write реализует интерфейс Sink. Запись кодируется в событие HEC с метаданными
+func (s *SplunkSink) Write(r *Record) error {
+	var b bytes.Buffer
+	b.WriteString(`{"time":`)
+	b.WriteString(splunkTime(r.Time))
+	writeJSONField(&b, "host", s.cfg.Host, false)
+	if s.cfg.Source != "" {
+		writeJSONField(&b, "source", s.cfg.Source, false)
+	}
+	writeJSONField(&b, "sourcetype", s.cfg.SourceType, false)
+	if s.cfg.Index != "" {
+		writeJSONField(&b, "index", s.cfg.Index, false)
+	}
+	b.WriteString(`,"event":`)
+	event := []byte(s.cfg.Encoder.Encode(r))
+	if json.Valid(event) {
+		b.Write(event)
+	} else {
+		b.Write(marshalJSON(string(event)))
+	}
+	b.WriteString("}\n")
+
+	return s.add(batchEntry{record: r, data: b.Bytes()})
+}
+
+// request объединяет события пакета в тело запроса
+func (s *SplunkSink) request(batch []batchEntry) (func() (*http.Request, error), error) {
+	var body bytes.Buffer
+	for _, e := range batch {
+		body.Write(e.data)
+	}
+	return newBodyRequest(http.MethodPost, s.cfg.URL+splunkEventPath, "application/json", s.headers, body.Bytes(), false)
+}
+
+// trackAck запоминает ackId отправленного пакета; пакет учитывается как доставленный
+// после подтверждения индексации или как недоставленный по истечении AckTimeout
+func (s *SplunkSink) trackAck(body []byte, batch []batchEntry) (int, error) {
+	var resp splunkResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return len(batch), fmt.Errorf("некорректный ответ HEC: %w", err)
+	}
+	if resp.AckID == nil {
+		return len(batch), fmt.Errorf("ответ HEC не содержит ackId, подтверждения индексации отключены для токена")
+	}
+
+	s.mu.Lock()
+	s.pending[*resp.AckID] = splunkPending{records: len(batch), deadline: time.Now().Add(s.cfg.AckTimeout)}
+	s.mu.Unlock()
+	s.notify()
+	return -1, nil
+}
+
+// notify будит опрос подтверждений
+func (s *SplunkSink) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pause выдерживает паузу между опросами; возвращает false, если опрос прерван при закрытии
+func (s *SplunkSink) pause(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-s.abort:
+		return false
+	}
+}
+
+// pollAcks опрашивает канал подтверждений, пока есть неподтвержденные пакеты.
+// Завершается после закрытия, когда все пакеты подтверждены или отброшены
+func (s *SplunkSink) pollAcks() {
+	defer close(s.done)
+	for {
+		s.mu.Lock()
+		idle, closing := len(s.pending) == 0, s.closing
+		s.mu.Unlock()
+
+		if idle {
+			if closing {
+				return
+			}
+			select {
+			case <-s.wake:
+			case <-s.abort:
+				return
+			}
+			continue
+		}
+		if !s.pause(s.cfg.AckInterval) {
+			s.expireAcks(time.Time{})
+			return
+		}
+		if err := s.queryAcks(); err != nil {
+			reportSinkError("splunk", err)
+		}
+		s.expireAcks(time.Now())
+	}
+}
+
+// queryAcks запрашивает подтверждения всех неподтвержденных пакетов одним запросом
+func (s *SplunkSink) queryAcks() error {
+	s.mu.Lock()
+	ids := make([]int64, 0, len(s.pending))
+	for id := range s.pending {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	body, err := json.Marshal(map[string][]int64{"acks": ids})
+	if err != nil {
+		return err
+	}
+	query, err := newBodyRequest(http.MethodPost, s.cfg.URL+splunkAckPath+"?channel="+s.cfg.Channel,
+		"application/json", s.headers, body, false)
+	if err != nil {
+		return err
+	}
+	if body, err = s.poster.do(query); err != nil {
+		return fmt.Errorf("не удалось запросить подтверждения: %w", err)
+	}
+	var acks struct {
+		Acks map[string]bool `json:"acks"`
+	}
+	if err := json.Unmarshal(body, &acks); err != nil {
+		return fmt.Errorf("некорректный ответ HEC на запрос подтверждения: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if p, ok := s.pending[id]; ok && acks.Acks[strconv.FormatInt(id, 10)] {
+			s.settle(p.records, 0)
+			delete(s.pending, id)
+		}
+	}
+	return nil
+}
+
+// expireAcks отбрасывает пакеты, не подтвержденные к моменту now;
+// нулевое время отбрасывает все неподтвержденные пакеты
+func (s *SplunkSink) expireAcks(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, p := range s.pending {
+		if now.IsZero() || now.After(p.deadline) {
+			s.settle(0, p.records)
+			delete(s.pending, id)
+			reportSinkError("splunk", fmt.Errorf("пакет %d не подтвержден за %s", id, s.cfg.AckTimeout))
+		}
+	}
+}
+
+// Close реализует интерфейс Sink. При включенных подтверждениях после отправки
+// накопленных записей ожидает подтверждения не дольше AckTimeout; неподтвержденные
+// пакеты отбрасываются, и возвращается ошибка
+func (s *SplunkSink) Close() error {
+	if !s.cfg.Ack {
+		return s.batchSender.Close()
+	}
+
+	before := s.Stats().DroppedRecords
+	err := s.batchSender.Close()
+	s.mu.Lock()
+	s.closing = true
+	s.mu.Unlock()
+	s.notify()
+
+	timer := time.NewTimer(s.cfg.AckTimeout)
+	defer timer.Stop()
+	select {
+	case <-s.done:
+	case <-timer.C:
+		s.aborted.Do(func() { close(s.abort) })
+		<-s.done
+	}
+
+	if dropped := s.Stats().DroppedRecords - before; err == nil && dropped > 0 {
+		err = &WriteError{Message: fmt.Sprintf("назначение splunk: отброшено записей: %d", dropped)}
+	}
+	return err
+}
+
+// splunkTime форматирует время в секундах Unix с дробной частью до микросекунд
+func splunkTime(t time.Time) string {
+	return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/1000)
+}
+
+// newSplunkChannel создает случайный идентификатор канала в формате GUID
+func newSplunkChannel() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
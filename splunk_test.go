@@ -0,0 +1,201 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hecServer фейковый HTTP Event Collector. Каждый пакет получает новый ackId;
+// подтверждения выдаются начиная с опроса ackAfter, отрицательное значение
+// означает, что подтверждений нет
+type hecServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	events   []map[string]interface{}
+	header   http.Header
+	channel  string
+	ackAfter int
+	polls    int
+	nextAck  int64
+	// queries идентификаторы, запрошенные в каждом опросе
+	queries [][]int64
+}
+
+func newHECServer(t *testing.T, ackAfter int) *hecServer {
+	s := &hecServer{ackAfter: ackAfter}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if r.Header.Get("Authorization") != "Splunk secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"text":"Invalid authorization","code":3}`)
+			return
+		}
+
+		switch r.URL.Path {
+		case splunkEventPath:
+			s.header = r.Header.Clone()
+			dec := json.NewDecoder(r.Body)
+			for dec.More() {
+				var event map[string]interface{}
+				require.NoError(t, dec.Decode(&event))
+				s.events = append(s.events, event)
+			}
+			if r.Header.Get("X-Splunk-Request-Channel") != "" {
+				s.nextAck++
+				_, _ = io.WriteString(w, `{"text":"Success","code":0,"ackId":`+itoa(int(s.nextAck))+`}`)
+				return
+			}
+			_, _ = io.WriteString(w, `{"text":"Success","code":0}`)
+		case splunkAckPath:
+			s.channel = r.URL.Query().Get("channel")
+			var query struct {
+				Acks []int64 `json:"acks"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&query))
+			s.queries = append(s.queries, query.Acks)
+			s.polls++
+			acked := s.ackAfter >= 0 && s.polls >= s.ackAfter
+			acks := map[string]bool{}
+			for _, id := range query.Acks {
+				acks[itoa(int(id))] = acked
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"acks": acks})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestSplunkSinkEvents(t *testing.T) {
+	server := newHECServer(t, 0)
+
+	sink, err := NewSplunkSink(SplunkConfig{
+		URL:    server.URL + "/",
+		Token:  "secret",
+		Host:   "web-1",
+		Source: "billing",
+		Index:  "main",
+	})
+	require.NoError(t, err)
+
+	l := New().WithSink(sink)
+	l.WithPrefix("API").WithFields(Fields{"user": "bob"}).Info("first")
+	sink.cfg.Encoder = TextEncoder{}
+	l.Warning("second")
+	require.NoError(t, l.Close())
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	require.Len(t, server.events, 2)
+
+	first := server.events[0]
+	assert.Equal(t, "web-1", first["host"])
+	assert.Equal(t, "billing", first["source"])
+	assert.Equal(t, DefaultSplunkSourceType, first["sourcetype"])
+	assert.Equal(t, "main", first["index"])
+	assert.InDelta(t, float64(time.Now().UnixNano())/1e9, first["time"], 60)
+	event := first["event"].(map[string]interface{})
+	assert.Equal(t, "first", event[MessageKey])
+	assert.Equal(t, "API", event[PrefixKey])
+	assert.Equal(t, "bob", event["user"])
+
+	// Текстовое представление передается строкой
+	assert.True(t, strings.HasSuffix(server.events[1]["event"].(string), "[WARNING] second"))
+	assert.Empty(t, server.header.Get("X-Splunk-Request-Channel"))
+	assert.Equal(t, uint64(2), sink.Stats().SentRecords)
+}
+
+func TestSplunkSinkAck(t *testing.T) {
+	server := newHECServer(t, 3)
+
+	sink, err := NewSplunkSink(SplunkConfig{URL: server.URL, Token: "secret", Ack: true, AckInterval: time.Millisecond})
+	require.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, sink.cfg.Channel)
+
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: InfoLevel, Message: "acked"}))
+	require.NoError(t, sink.Close())
+
+	server.mu.Lock()
+	assert.Equal(t, sink.cfg.Channel, server.header.Get("X-Splunk-Request-Channel"))
+	assert.Equal(t, sink.cfg.Channel, server.channel)
+	assert.Equal(t, 3, server.polls)
+	assert.Equal(t, []int64{1}, server.queries[0])
+	server.mu.Unlock()
+
+	stats := sink.Stats()
+	assert.Equal(t, uint64(1), stats.SentRecords)
+	assert.Zero(t, stats.DroppedRecords)
+}
+
+func TestSplunkSinkAckTimeout(t *testing.T) {
+	server := newHECServer(t, -1)
+
+	sink, err := NewSplunkSink(SplunkConfig{
+		URL:         server.URL,
+		Token:       "secret",
+		Ack:         true,
+		Channel:     "11111111-2222-4333-8444-555555555555",
+		AckInterval: time.Millisecond,
+		AckTimeout:  20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: InfoLevel, Message: "unacked"}))
+	assert.Error(t, sink.Close())
+
+	stats := sink.Stats()
+	assert.Zero(t, stats.SentRecords)
+	assert.Equal(t, uint64(1), stats.DroppedRecords)
+}
+
+func TestSplunkSinkAckDoesNotBlockBatches(t *testing.T) {
+	server := newHECServer(t, 10)
+
+	sink, err := NewSplunkSink(SplunkConfig{
+		URL:         server.URL,
+		Token:       "secret",
+		Ack:         true,
+		BatchConfig: BatchConfig{MaxRecords: 1},
+		AckInterval: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	for _, msg := range []string{"a", "b", "c"} {
+		require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: InfoLevel, Message: msg}))
+	}
+	// пакеты отправляются, не дожидаясь подтверждения предыдущих
+	assert.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.events) == 3
+	}, 40*time.Millisecond, time.Millisecond)
+	require.NoError(t, sink.Close())
+
+	server.mu.Lock()
+	// неподтвержденные пакеты запрашиваются одним запросом
+	assert.Contains(t, server.queries, []int64{1, 2, 3})
+	server.mu.Unlock()
+	assert.Equal(t, uint64(3), sink.Stats().SentRecords)
+}
+
+func TestSplunkSinkConfig(t *testing.T) {
+	var cfgErr *ConfigError
+	_, err := NewSplunkSink(SplunkConfig{Token: "secret"})
+	assert.ErrorAs(t, err, &cfgErr)
+	_, err = NewSplunkSink(SplunkConfig{URL: "http://localhost:8088"})
+	assert.ErrorAs(t, err, &cfgErr)
+
+	assert.Equal(t, "1714564800.123456", splunkTime(time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)))
+}
@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// packagePath путь пакета логгера, используемый для фильтрации собственных кадров стека
+var packagePath = reflect.TypeOf(Logger{}).PkgPath()
+
+// maxStackDepth максимальное количество кадров в трассировке стека
+const maxStackDepth = 64
+
+// captureStack возвращает трассировку стека горутины, пропуская skip кадров над
+// вызывающей функцией. Кадры самого логгера и среды выполнения Go отфильтровываются
+func captureStack(skip int) string {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+2, pcs)
+	return formatFrames(pcs[:n])
+}
+
+// formatFrames форматирует кадры стека в виде, привычном по выводу panic:
+// имя функции на одной строке и путь к файлу с отступом на следующей
+func formatFrames(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if !skipFrame(frame) {
+			if b.Len() > 0 {
+				b.WriteByte('\n')
+			}
+			fmt.Fprintf(&b, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// skipFrame сообщает, нужно ли исключить кадр из трассировки
+func skipFrame(frame runtime.Frame) bool {
+	if frame.Function == "" || strings.HasPrefix(frame.Function, "runtime.") {
+		return true
+	}
+	// Тесты пакета относятся к коду пользователя, остальные кадры пакета скрываем
+	return strings.HasPrefix(frame.Function, packagePath+".") && !strings.HasSuffix(frame.File, "_test.go")
+}
+
+// errorStack ищет среди полей записи ошибку, несущую собственную трассировку стека,
+// и возвращает самую глубокую трассировку в цепочке ошибок
+func errorStack(fields map[string]interface{}) string {
+	for _, k := range sortedKeys(fields) {
+		if err, ok := fields[k].(error); ok {
+			if stack := deepestStack(err, 0); stack != "" {
+				return stack
+			}
+		}
+	}
+	return ""
+}
+
+// deepestStack возвращает трассировку ближайшей к источнику ошибки в цепочке причин
+func deepestStack(err error, depth int) string {
+	if depth < maxErrorDepth {
+		for _, cause := range unwrapAll(err) {
+			if stack := deepestStack(cause, depth+1); stack != "" {
+				return stack
+			}
+		}
+	}
+	return stackOf(err)
+}
+
+// stackOf возвращает трассировку ошибки, если у нее есть метод StackTrace().
+// Поддерживаются методы, возвращающие string, []uintptr, а также любые типы,
+// форматируемые через "%+v" (например, errors.StackTrace из github.com/pkg/errors)
+func stackOf(err error) string {
+	if nilError(err) {
+		return ""
+	}
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return ""
+	}
+
+	switch trace := method.Call(nil)[0].Interface().(type) {
+	case string:
+		return strings.TrimSpace(trace)
+	case []uintptr:
+		return formatFrames(trace)
+	default:
+		return strings.TrimSpace(fmt.Sprintf("%+v", trace))
+	}
+}
+
+// WithStacktrace создает новый логгер, добавляющий трассировку стека к записям
+// уровня level и выше
+func (l *Logger) WithStacktrace(level Level) *Logger {
+	newLogger := l.clone()
+	newLogger.stacktraceLevel = level
+	newLogger.addStacktrace = true
+	return newLogger
+}
@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stackError ошибка, несущая собственную трассировку стека
+type stackError struct {
+	msg   string
+	stack string
+}
+
+func (e *stackError) Error() string      { return e.msg }
+func (e *stackError) StackTrace() string { return e.stack }
+
+// pcsError ошибка с трассировкой в виде программных счетчиков
+type pcsError struct {
+	pcs []uintptr
+}
+
+func (e *pcsError) Error() string         { return "pcs error" }
+func (e *pcsError) StackTrace() []uintptr { return e.pcs }
+func newPCSError() *pcsError              { return &pcsError{pcs: callersHere()} }
+func callersHere() []uintptr              { pcs := make([]uintptr, 16); return pcs[:runtime.Callers(1, pcs)] }
+
+func TestStacktrace(t *testing.T) {
+	t.Run("трассировка для уровня ERROR и выше", func(t *testing.T) {
+		l := New().WithStacktrace(ErrorLevel)
+		l.Info("no stack")
+		l.Error("with stack")
+
+		messages := l.GetMessages()
+		require.Len(t, messages, 2)
+		assert.NotContains(t, messages[0], "\n")
+
+		lines := strings.Split(messages[1], "\n")
+		require.Greater(t, len(lines), 2)
+		assert.Contains(t, lines[0], "[ERROR] with stack")
+		assert.Contains(t, lines[1], "TestStacktrace")
+		assert.Contains(t, lines[2], "stacktrace_test.go:")
+		// Кадры логгера и среды выполнения отфильтрованы
+		assert.NotContains(t, messages[1], packagePath+".(*Logger)")
+		assert.NotContains(t, messages[1], "runtime.goexit")
+	})
+
+	t.Run("трассировка из ошибки имеет приоритет", func(t *testing.T) {
+		l := New().WithStacktrace(ErrorLevel)
+		err := fmt.Errorf("wrapped: %w", &stackError{msg: "inner", stack: "origin.Func\n\torigin.go:10"})
+		child := l.WithFields(map[string]interface{}{"error": err})
+		child.Error("failed")
+
+		messages := child.GetMessages()
+		require.Len(t, messages, 1)
+		assert.True(t, strings.HasSuffix(messages[0], "\n\torigin.Func\n\t\torigin.go:10"))
+	})
+
+	t.Run("трассировка из программных счетчиков", func(t *testing.T) {
+		l := New().WithStacktrace(ErrorLevel)
+		child := l.WithFields(map[string]interface{}{"error": newPCSError()})
+		child.Error("failed")
+
+		messages := child.GetMessages()
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], "newPCSError")
+	})
+
+	t.Run("трассировка в JSON", func(t *testing.T) {
+		l := New().WithConfig(&Config{Level: "debug", Format: FormatJSON, StacktraceLevel: "fatal"})
+		originalOsExit := osExit
+		defer func() { osExit = originalOsExit }()
+		osExit = func(code int) {}
+
+		l.Error("no stack")
+		l.Fatal("with stack")
+
+		messages := l.GetMessages()
+		require.Len(t, messages, 2)
+
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(messages[0]), &decoded))
+		assert.NotContains(t, decoded, StacktraceKey)
+		require.NoError(t, json.Unmarshal([]byte(messages[1]), &decoded))
+		assert.Contains(t, decoded[StacktraceKey], "stacktrace_test.go:")
+	})
+}
+
+func TestConfigValidateStacktraceLevel(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.StacktraceLevel = "error"
+	require.NoError(t, cfg.Validate())
+
+	cfg.StacktraceLevel = "loud"
+	require.Error(t, cfg.Validate())
+}
@@ -0,0 +1,358 @@
+package logger
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Форматы сообщений syslog
+const (
+	SyslogRFC5424 = "rfc5424"
+	SyslogRFC3164 = "rfc3164"
+)
+
+// SyslogFacility источник сообщений syslog
+type SyslogFacility int
+
+// Источники сообщений syslog. Источник kern не предназначен для приложений,
+// поэтому нулевое значение в SyslogConfig означает FacilityUser
+const (
+	FacilityUser SyslogFacility = iota + 1
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLpr
+	FacilityNews
+	FacilityUucp
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFtp
+	FacilityLocal0 SyslogFacility = iota + 5
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// DefaultSyslogSDID идентификатор блока структурированных данных RFC 5424.
+// Номер 32473 зарезервирован для примеров и внутреннего использования (RFC 5612)
+const DefaultSyslogSDID = "fields@32473"
+
+// syslogTimeFormat формат TIMESTAMP RFC 5424: дробная часть секунды не длиннее 6 цифр
+const syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// DefaultSyslogTimeout таймаут подключения и записи по умолчанию
+const DefaultSyslogTimeout = 5 * time.Second
+
+// syslogLocalPaths пути к локальному сокету syslog в порядке проверки
+var syslogLocalPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogConfig параметры подключения к syslog
+type SyslogConfig struct {
+	// Network тип соединения: "unix", "unixgram", "udp" или "tcp".
+	// Пустое значение означает локальный сокет syslog
+	Network string
+	// Address адрес сервера или путь к сокету
+	Address string
+	// Format формат сообщений: "rfc5424" (по умолчанию) или "rfc3164"
+	Format string
+	// Facility источник сообщений, по умолчанию FacilityUser
+	Facility SyslogFacility
+	// AppName имя приложения; по умолчанию префикс записи или DefaultPrefix
+	AppName string
+	// Hostname имя хоста, по умолчанию os.Hostname
+	Hostname string
+	// SDID идентификатор блока структурированных данных, по умолчанию DefaultSyslogSDID
+	SDID string
+	// Timeout таймаут подключения и записи, по умолчанию DefaultSyslogTimeout
+	Timeout time.Duration
+}
+
+// SyslogSink передает записи в syslog. При ошибке записи соединение
+// переустанавливается и запись повторяется один раз
+type SyslogSink struct {
+	cfg  SyslogConfig
+	conn net.Conn
+	pid  int
+	mu   sync.Mutex
+	// stream потоковое соединение, требующее разделения сообщений
+	stream bool
+	closed bool
+}
+
+// NewSyslogSink создает назначение syslog и устанавливает соединение
+func NewSyslogSink(cfg SyslogConfig) (*SyslogSink, error) {
+	switch strings.ToLower(cfg.Format) {
+	case "":
+		cfg.Format = SyslogRFC5424
+	case SyslogRFC5424, SyslogRFC3164:
+		cfg.Format = strings.ToLower(cfg.Format)
+	default:
+		return nil, &ConfigError{Err: fmt.Errorf("неизвестный формат syslog: %s", cfg.Format), Reason: "некорректный формат syslog"}
+	}
+	if cfg.Facility == 0 {
+		cfg.Facility = FacilityUser
+	}
+	if cfg.Facility < 0 || cfg.Facility > FacilityLocal7 {
+		return nil, &ConfigError{Err: fmt.Errorf("источник вне диапазона: %d", cfg.Facility), Reason: "некорректный источник syslog"}
+	}
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+	if cfg.SDID == "" {
+		cfg.SDID = DefaultSyslogSDID
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultSyslogTimeout
+	}
+
+	s := &SyslogSink{cfg: cfg, pid: os.Getpid()}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect устанавливает соединение согласно конфигурации
+func (s *SyslogSink) connect() error {
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch s.cfg.Network {
+	case "":
+		conn, err = dialLocalSyslog(s.cfg.Timeout)
+	case "unix":
+		// Сокет syslog обычно датаграммный, но встречается и потоковый
+		conn, err = net.DialTimeout("unixgram", s.cfg.Address, s.cfg.Timeout)
+		if err != nil {
+			conn, err = net.DialTimeout("unix", s.cfg.Address, s.cfg.Timeout)
+		}
+	default:
+		conn, err = net.DialTimeout(s.cfg.Network, s.cfg.Address, s.cfg.Timeout)
+	}
+	if err != nil {
+		return &WriteError{Message: "не удалось подключиться к syslog", Cause: err}
+	}
+
+	if addr := conn.RemoteAddr(); addr != nil {
+		s.stream = addr.Network() == "tcp" || addr.Network() == "unix"
+	}
+	s.conn = conn
+	return nil
+}
+
+// dialLocalSyslog подключается к первому доступному локальному сокету syslog
+func dialLocalSyslog(timeout time.Duration) (net.Conn, error) {
+	var lastErr error
+	for _, path := range syslogLocalPaths {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := net.DialTimeout(network, path, timeout)
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+// Write реализует интерфейс Sink
+func (s *SyslogSink) Write(r *Record) error {
+	msg := s.format(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return &WriteError{Message: "соединение с syslog закрыто"}
+	}
+	if s.conn != nil {
+		if err := s.send(msg); err == nil {
+			return nil
+		}
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	if err := s.connect(); err != nil {
+		return err
+	}
+	if err := s.send(msg); err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return &WriteError{Message: "не удалось отправить запись в syslog", Cause: err}
+	}
+	return nil
+}
+
+// send отправляет сообщение с разделением, требуемым для потоковых соединений
+func (s *SyslogSink) send(msg string) error {
+	if s.stream {
+		if s.cfg.Format == SyslogRFC5424 {
+			// Подсчет октетов по RFC 6587 допускает переводы строк внутри сообщения
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		} else {
+			msg += "\n"
+		}
+	}
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.cfg.Timeout)); err != nil {
+		return err
+	}
+	_, err := s.conn.Write([]byte(msg))
+	return err
+}
+
+// Close реализует интерфейс Sink
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// format кодирует запись в выбранный формат syslog
+func (s *SyslogSink) format(r *Record) string {
+	pri := int(s.cfg.Facility)*8 + syslogSeverity(r.Level)
+	appName := s.cfg.AppName
+	if appName == "" {
+		appName = r.Prefix
+	}
+	if appName == "" {
+		appName = DefaultPrefix
+	}
+
+	if s.cfg.Format == SyslogRFC3164 {
+		return s.format3164(r, pri, appName)
+	}
+	return s.format5424(r, pri, appName)
+}
+
+// format5424 кодирует запись по RFC 5424:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ID k="v" ...] MSG
+func (s *SyslogSink) format5424(r *Record, pri int, appName string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d - ",
+		pri,
+		r.Time.Format(syslogTimeFormat),
+		syslogHeaderField(s.cfg.Hostname, 255),
+		syslogHeaderField(appName, 48),
+		s.pid,
+	)
+
+	pairs := flatFields(r)
+	if r.Prefix != "" {
+		pairs = append([]fieldPair{{Key: PrefixKey, Value: r.Prefix}}, pairs...)
+	}
+	if len(pairs) == 0 {
+		b.WriteByte('-')
+	} else {
+		b.WriteString("[" + syslogSDName(s.cfg.SDID))
+		for _, p := range pairs {
+			b.WriteString(" " + syslogSDName(p.Key) + `="` + sdValueEscaper.Replace(p.Value) + `"`)
+		}
+		b.WriteByte(']')
+	}
+
+	if msg := syslogMessage(r); msg != "" {
+		b.WriteByte(' ')
+		b.WriteString(msg)
+	}
+	return b.String()
+}
+
+// format3164 кодирует запись по RFC 3164: <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG k=v ...
+// Структурированных данных в этом формате нет, поэтому поля добавляются к тексту
+func (s *SyslogSink) format3164(r *Record, pri int, appName string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>%s %s %s[%d]: ",
+		pri,
+		r.Time.Format(time.Stamp),
+		syslogHeaderField(s.cfg.Hostname, 255),
+		syslogHeaderField(appName, 32),
+		s.pid,
+	)
+	b.WriteString(ControlEscape.sanitize(r.Message))
+	for _, p := range flatFields(r) {
+		b.WriteString(" " + ControlEscape.sanitize(p.Key+"="+p.Value))
+	}
+	return b.String()
+}
+
+// syslogSeverity сопоставляет уровень записи важности syslog
+func syslogSeverity(level Level) int {
+	switch level {
+	case DebugLevel:
+		return 7
+	case InfoLevel:
+		return 6
+	case WarningLevel:
+		return 4
+	case ErrorLevel:
+		return 3
+	case FatalLevel:
+		return 2
+	default:
+		return 5
+	}
+}
+
+// syslogMessage возвращает текст сообщения; трассировка стека добавляется
+// отдельными строками, так как сообщение RFC 5424 может быть многострочным
+func syslogMessage(r *Record) string {
+	if r.Stack == "" {
+		return r.Message
+	}
+	return r.Message + "\n" + r.Stack
+}
+
+// syslogHeaderField приводит значение заголовка к печатному ASCII без пробелов
+// и ограничивает его длину; пустое значение заменяется на "-"
+func syslogHeaderField(v string, max int) string {
+	b := make([]byte, 0, len(v))
+	for i := 0; i < len(v) && len(b) < max; i++ {
+		if c := v[i]; c > ' ' && c < 0x7f {
+			b = append(b, c)
+		} else {
+			b = append(b, '_')
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
+
+// syslogSDName приводит имя к формату SD-NAME: до 32 печатных символов ASCII
+// без '=', ' ', ']' и '"'
+func syslogSDName(v string) string {
+	b := make([]byte, 0, len(v))
+	for i := 0; i < len(v) && len(b) < 32; i++ {
+		c := v[i]
+		if c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	if len(b) == 0 {
+		return "_"
+	}
+	return string(b)
+}
+
+// sdValueEscaper экранирует значение параметра структурированных данных
+var sdValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
@@ -0,0 +1,172 @@
+package logger
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syslogTestRecord создает запись с фиксированным временем
+func syslogTestRecord(level Level, msg string, fields map[string]interface{}) *Record {
+	return &Record{
+		Time:    time.Date(2024, 3, 5, 7, 8, 9, 0, time.UTC),
+		Level:   level,
+		Prefix:  "API",
+		Message: msg,
+		Fields:  fields,
+	}
+}
+
+func TestSyslogRFC5424OverUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	sink, err := NewSyslogSink(SyslogConfig{
+		Network:  "udp",
+		Address:  pc.LocalAddr().String(),
+		Facility: FacilityLocal0,
+		Hostname: "web 1",
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write(syslogTestRecord(ErrorLevel, "payment failed", map[string]interface{}{
+		"order": 42,
+		"note":  `a "quoted" ]value`,
+		"err":   errors.New("declined"),
+	})))
+
+	buf := make([]byte, 4096)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+
+	want := "<131>1 2024-03-05T07:08:09.000000Z web_1 API " + strconv.Itoa(os.Getpid()) + " - " +
+		`[fields@32473 prefix="API" err="declined" err.type="*errors.errorString" note="a \"quoted\" \]value" order="42"] payment failed`
+	assert.Equal(t, want, string(buf[:n]))
+}
+
+func TestSyslogRFC5424Timestamp(t *testing.T) {
+	s := &SyslogSink{cfg: SyslogConfig{Facility: FacilityUser, Hostname: "host"}, pid: 7}
+	r := syslogTestRecord(InfoLevel, "msg", nil)
+	r.Time = time.Date(2024, 1, 1, 0, 0, 0, 123456789, time.FixedZone("", 3*3600))
+
+	// TIME-SECFRAC допускает не более 6 цифр
+	assert.True(t, strings.HasPrefix(s.format(r), "<14>1 2024-01-01T00:00:00.123456+03:00 host API 7 - "), s.format(r))
+}
+
+func TestSyslogRFC3164(t *testing.T) {
+	s := &SyslogSink{cfg: SyslogConfig{Format: SyslogRFC3164, Facility: FacilityUser, Hostname: "host"}, pid: 7}
+	r := syslogTestRecord(WarningLevel, "slow\nquery", map[string]interface{}{"ms": 1500})
+	r.Prefix = ""
+
+	assert.Equal(t, `<12>Mar  5 07:08:09 host APP[7]: slow\nquery ms=1500`, s.format(r))
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	assert.Equal(t, 7, syslogSeverity(DebugLevel))
+	assert.Equal(t, 6, syslogSeverity(InfoLevel))
+	assert.Equal(t, 4, syslogSeverity(WarningLevel))
+	assert.Equal(t, 3, syslogSeverity(ErrorLevel))
+	assert.Equal(t, 2, syslogSeverity(FatalLevel))
+}
+
+func TestSyslogTCPOctetCountingAndReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	received := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// Каждое соединение читает одно сообщение и закрывается
+			r := bufio.NewReader(conn)
+			size, err := r.ReadString(' ')
+			if err == nil {
+				n, _ := strconv.Atoi(strings.TrimSpace(size))
+				msg := make([]byte, n)
+				if _, err := io.ReadFull(r, msg); err == nil {
+					received <- string(msg)
+				}
+			}
+			conn.Close()
+		}
+	}()
+
+	sink, err := NewSyslogSink(SyslogConfig{Network: "tcp", Address: ln.Addr().String(), Hostname: "h"})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write(syslogTestRecord(InfoLevel, "first\nline", nil)))
+	select {
+	case msg := <-received:
+		assert.True(t, strings.HasSuffix(msg, "[fields@32473 prefix=\"API\"] first\nline"), msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("первое сообщение не получено")
+	}
+
+	// Сервер закрыл соединение: запись либо сразу переподключается,
+	// либо теряется в буфере сокета до обнаружения разрыва
+	deadline := time.After(5 * time.Second)
+	for {
+		_ = sink.Write(syslogTestRecord(InfoLevel, "again", nil))
+		select {
+		case msg := <-received:
+			assert.True(t, strings.HasSuffix(msg, " again"), msg)
+			return
+		case <-deadline:
+			t.Fatal("сообщение после переподключения не получено")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+func TestSyslogUnixgram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sink, err := NewSyslogSink(SyslogConfig{Network: "unix", Address: path, Format: "RFC3164", AppName: "svc"})
+	require.NoError(t, err)
+
+	l := New().WithSink(sink)
+	l.Info("hello")
+	require.NoError(t, l.Close())
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Contains(t, string(buf[:n]), " svc[")
+	assert.True(t, strings.HasSuffix(string(buf[:n]), ": hello"))
+
+	assert.Error(t, sink.Write(syslogTestRecord(InfoLevel, "late", nil)))
+}
+
+func TestSyslogConfigErrors(t *testing.T) {
+	var cfgErr *ConfigError
+	_, err := NewSyslogSink(SyslogConfig{Format: "rfc1"})
+	assert.ErrorAs(t, err, &cfgErr)
+	_, err = NewSyslogSink(SyslogConfig{Facility: 24})
+	assert.ErrorAs(t, err, &cfgErr)
+
+	var writeErr *WriteError
+	_, err = NewSyslogSink(SyslogConfig{Network: "unix", Address: filepath.Join(t.TempDir(), "missing.sock")})
+	assert.ErrorAs(t, err, &writeErr)
+}
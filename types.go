@@ -1,9 +1,11 @@
 package logger
 
 import (
+	"io"
 	"log"
 	"os"
 	"sync"
+	"time"
 )
 
 // Fields представляет собой набор дополнительных полей для логирования
@@ -27,6 +29,8 @@ type ILogger interface {
 	Error(args ...interface{})
 	// Errorf logs a formatted message at ERROR level
 	Errorf(format string, args ...interface{})
+	// Errorw logs a message at ERROR level with fields passed as key-value pairs
+	Errorw(msg string, keysAndValues ...interface{})
 	// Fatal logs a message at FATAL level and terminates the program
 	Fatal(args ...interface{})
 	// Fatalf logs a formatted message at FATAL level and terminates the program
@@ -49,6 +53,15 @@ type ILogger interface {
 	WithTimeFormat(format string) ILogger
 	// GetMessages returns the messages logged by the logger
 	GetMessages() []string
+	// Enabled reports whether messages of the specified level are logged
+	Enabled(level Level) bool
+	// DebugEnabled reports whether DEBUG level is enabled
+	DebugEnabled() bool
+	// WithCaller creates a new logger that annotates records with the call site
+	WithCaller(enabled bool) ILogger
+	// WithCallerSkip creates a new logger that skips additional stack frames
+	// when determining the call site
+	WithCallerSkip(skip int) ILogger
 }
 
 // Level представляет уровень логирования
@@ -74,6 +87,16 @@ const (
 
 // Logger реализует интерфейс ILogger и предоставляет функциональность для логирования
 type Logger struct {
+	encoder    Encoder
+	redactor   *Redactor
+	sampler    *Sampler
+	dedup      *Deduplicator
+	limiter    *RateLimiter
+	hooks      []*hookEntry
+	hookOwner  *hookOwner
+	filters    []Filter
+	sinks      []Sink
+	metrics    *Metrics
 	mu         *sync.RWMutex
 	logger     *log.Logger
 	file       *os.File
@@ -83,14 +106,55 @@ type Logger struct {
 	timeFormat string
 	messages   []string
 	level      Level
+	control    ControlPolicy
+	callerSkip int
 	messagesMu sync.RWMutex
 	fileMu     sync.Mutex
+	addCaller  bool
+
+	stacktraceLevel Level
+	addStacktrace   bool
+
+	stdout      io.Writer
+	stderr      io.Writer
+	stderrLevel Level
+	splitStderr bool
 }
 
 // Config представляет конфигурацию логгера
 type Config struct {
 	Files map[string]string
 	Level string
+	// Format формат вывода: "text" (по умолчанию) или "json"
+	Format string
+	// ControlChars обработка управляющих символов в текстовом формате:
+	// "escape" (по умолчанию), "strip" или "raw"
+	ControlChars string
+	// Sampling параметры сэмплирования; nil отключает сэмплирование
+	Sampling *SamplingConfig
+	// RateLimit ограничения потока записей по префиксам; nil снимает ограничения
+	RateLimit *RateLimitConfig
+	// DedupWindow окно подавления подряд идущих одинаковых записей; 0 отключает подавление
+	DedupWindow time.Duration
+	// Redact правила маскирования чувствительных данных; nil отключает маскирование
+	Redact *RedactConfig
+	// StacktraceLevel уровень, начиная с которого к записям добавляется
+	// трассировка стека; пустое значение отключает трассировку
+	StacktraceLevel string
+	// Caller добавляет к записям место вызова
+	Caller bool
+	// Filters правила отбора записей, проверяемые до кодирования
+	Filters []FilterRule
+	// FileSinks дополнительные файлы со своим форматом и правилами отбора
+	FileSinks []FileSinkConfig
+	// Metrics включает подсчет записей, доступный через Logger.Metrics
+	Metrics bool
+	// SplitStderr выводит в stderr записи уровня StderrLevel и выше,
+	// остальные записи выводятся в stdout
+	SplitStderr bool
+	// StderrLevel уровень, начиная с которого записи выводятся в stderr,
+	// по умолчанию "warning"
+	StderrLevel string
 }
 
 // WriteError представляет ошибку записи в лог
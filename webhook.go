@@ -0,0 +1,375 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// Форматы сообщений webhook
+const (
+	WebhookGeneric = "generic"
+	WebhookSlack   = "slack"
+	WebhookTeams   = "teams"
+)
+
+// Параметры назначения webhook по умолчанию
+const (
+	DefaultWebhookWindow    = 10 * time.Second
+	DefaultWebhookThrottle  = 5 * time.Minute
+	DefaultWebhookMaxAlerts = 20
+	DefaultWebhookTitle     = "Log alerts"
+	webhookQueueSize        = 16
+)
+
+// webhookTemplates встроенные шаблоны сообщений. Функция json сериализует значение,
+// join объединяет строки
+var webhookTemplates = map[string]string{
+	WebhookGeneric: `{"title":{{json .Title}},"host":{{json .Host}},"start":{{json .Start}},"end":{{json .End}},` +
+		`"total":{{.Total}},"omitted":{{.Omitted}},"suppressed":{{.Suppressed}},"alerts":[` +
+		`{{range $i, $a := .Alerts}}{{if $i}},{{end}}{"time":{{json $a.First}},"level":{{json $a.Level.String}},` +
+		`"prefix":{{json $a.Prefix}},"message":{{json $a.Message}},"count":{{$a.Count}},"fields":{{json $a.Fields}}}{{end}}]}`,
+	WebhookSlack: `{"text":{{json (printf "*%s*\n%s" .Title (join .Lines "\n"))}}}`,
+	WebhookTeams: `{"@type":"MessageCard","@context":"https://schema.org/extensions","themeColor":{{json .Color}},` +
+		`"summary":{{json .Title}},"title":{{json .Title}},"text":{{json (join .Lines "\n\n")}}}`,
+}
+
+// WebhookConfig параметры назначения оповещений через webhook
+type WebhookConfig struct {
+	RetryConfig
+	// Client HTTP-клиент, по умолчанию клиент с таймаутом DefaultHTTPTimeout
+	Client *http.Client
+	// Headers дополнительные заголовки запросов
+	Headers map[string]string
+	// Levels уровни, по которым отправляются оповещения, по умолчанию ERROR и FATAL
+	Levels []Level
+	// URL адрес webhook
+	URL string
+	// Format встроенный шаблон: WebhookGeneric (по умолчанию), WebhookSlack или WebhookTeams
+	Format string
+	// Template собственный шаблон text/template вместо встроенного; данными служит WebhookDigest
+	Template string
+	// Title заголовок сообщения, по умолчанию DefaultWebhookTitle
+	Title string
+	// Window окно группировки: записи, пришедшие за окно, отправляются одним
+	// сообщением, по умолчанию DefaultWebhookWindow
+	Window time.Duration
+	// Throttle период, в течение которого уже отправленное оповещение с тем же уровнем,
+	// префиксом и сообщением не повторяется, по умолчанию DefaultWebhookThrottle;
+	// отрицательное значение отключает подавление
+	Throttle time.Duration
+	// MaxAlerts максимальное количество различных оповещений в сообщении,
+	// по умолчанию DefaultWebhookMaxAlerts
+	MaxAlerts int
+}
+
+// WebhookAlert группа одинаковых записей в сообщении
+type WebhookAlert struct {
+	First   time.Time
+	Last    time.Time
+	Fields  map[string]interface{}
+	Prefix  string
+	Message string
+	Level   Level
+	Count   int
+}
+
+// WebhookDigest данные сообщения, передаваемые шаблону
+type WebhookDigest struct {
+	Start  time.Time
+	End    time.Time
+	Title  string
+	Host   string
+	Alerts []WebhookAlert
+	// Total количество записей за окно
+	Total int
+	// Omitted количество различных оповещений сверх MaxAlerts
+	Omitted int
+	// Suppressed количество повторов, подавленных с момента предыдущего сообщения
+	Suppressed int
+}
+
+// Lines возвращает текст сообщения построчно: сводку и по строке на оповещение
+func (d WebhookDigest) Lines() []string {
+	lines := []string{fmt.Sprintf("%d records on %s between %s and %s",
+		d.Total, d.Host, d.Start.Format(time.RFC3339), d.End.Format(time.RFC3339))}
+	for _, a := range d.Alerts {
+		line := "[" + a.Level.String() + "] "
+		if a.Prefix != "" {
+			line += "[" + a.Prefix + "] "
+		}
+		line += a.Message
+		if a.Count > 1 {
+			line += fmt.Sprintf(" (x%d)", a.Count)
+		}
+		lines = append(lines, line)
+	}
+	if d.Omitted > 0 {
+		lines = append(lines, fmt.Sprintf("... and %d more distinct alerts", d.Omitted))
+	}
+	if d.Suppressed > 0 {
+		lines = append(lines, fmt.Sprintf("%d repeated alerts suppressed", d.Suppressed))
+	}
+	return lines
+}
+
+// Color возвращает цвет сообщения по наиболее серьезному уровню
+func (d WebhookDigest) Color() string {
+	maxLevel := DebugLevel
+	for _, a := range d.Alerts {
+		if a.Level > maxLevel {
+			maxLevel = a.Level
+		}
+	}
+	switch maxLevel {
+	case FatalLevel:
+		return "8B0000"
+	case ErrorLevel:
+		return "D13438"
+	case WarningLevel:
+		return "FFA500"
+	default:
+		return "0078D7"
+	}
+}
+
+// WebhookStats счетчики назначения webhook
+type WebhookStats struct {
+	SentMessages    uint64
+	FailedMessages  uint64
+	Alerts          uint64
+	ThrottledAlerts uint64
+}
+
+// WebhookSink отправляет оповещения о записях выбранных уровней. Записи, пришедшие
+// за окно группировки, объединяются в одно сообщение, одинаковые записи
+// считаются вместе, а уже отправленные оповещения не повторяются в течение Throttle
+type WebhookSink struct {
+	stats      WebhookStats // первое поле для выравнивания атомарных счетчиков на 32-битных платформах
+	cfg        WebhookConfig
+	poster     *httpPoster
+	tmpl       *template.Template
+	levels     map[Level]bool
+	host       string
+	pending    *WebhookDigest
+	index      map[string]int
+	lastSent   map[string]time.Time
+	suppressed int
+	timer      *time.Timer
+	queue      chan WebhookDigest
+	done       chan struct{}
+	mu         sync.Mutex
+	closed     bool
+}
+
+// NewWebhookSink создает назначение webhook
+func NewWebhookSink(cfg WebhookConfig) (*WebhookSink, error) {
+	if cfg.URL == "" {
+		return nil, &ConfigError{Err: fmt.Errorf("не указан URL"), Reason: "некорректное назначение webhook"}
+	}
+	if len(cfg.Levels) == 0 {
+		cfg.Levels = []Level{ErrorLevel, FatalLevel}
+	}
+	if cfg.Format == "" {
+		cfg.Format = WebhookGeneric
+	}
+	source := cfg.Template
+	if source == "" {
+		var ok bool
+		if source, ok = webhookTemplates[cfg.Format]; !ok {
+			return nil, &ConfigError{Err: fmt.Errorf("неизвестный формат: %s", cfg.Format), Reason: "некорректное назначение webhook"}
+		}
+	}
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) string { return string(marshalJSON(v)) },
+		"join": strings.Join,
+	}).Parse(source)
+	if err != nil {
+		return nil, &ConfigError{Err: err, Reason: "некорректный шаблон webhook"}
+	}
+	if cfg.Title == "" {
+		cfg.Title = DefaultWebhookTitle
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultWebhookWindow
+	}
+	if cfg.Throttle == 0 {
+		cfg.Throttle = DefaultWebhookThrottle
+	}
+	if cfg.MaxAlerts <= 0 {
+		cfg.MaxAlerts = DefaultWebhookMaxAlerts
+	}
+
+	s := &WebhookSink{
+		cfg:      cfg,
+		poster:   newHTTPPoster(cfg.Client, cfg.RetryConfig),
+		tmpl:     tmpl,
+		levels:   make(map[Level]bool, len(cfg.Levels)),
+		lastSent: make(map[string]time.Time),
+		queue:    make(chan WebhookDigest, webhookQueueSize),
+		done:     make(chan struct{}),
+	}
+	for _, level := range cfg.Levels {
+		s.levels[level] = true
+	}
+	s.host, _ = os.Hostname()
+
+	go func() {
+		defer close(s.done)
+		for digest := range s.queue {
+			s.send(digest)
+		}
+	}()
+	return s, nil
+}
+
+// Write реализует интерфейс Sink. Записи других уровней пропускаются
+func (s *WebhookSink) Write(r *Record) error {
+	if !s.levels[r.Level] {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return &WriteError{Message: "назначение webhook закрыто"}
+	}
+	key := r.Level.String() + "\x00" + r.Prefix + "\x00" + r.Message
+	if s.cfg.Throttle > 0 {
+		if last, ok := s.lastSent[key]; ok && time.Since(last) < s.cfg.Throttle {
+			s.suppressed++
+			atomic.AddUint64(&s.stats.ThrottledAlerts, 1)
+			return nil
+		}
+	}
+	atomic.AddUint64(&s.stats.Alerts, 1)
+
+	if s.pending == nil {
+		s.pending = &WebhookDigest{Start: r.Time, Title: s.cfg.Title, Host: s.host}
+		s.index = make(map[string]int)
+		s.timer = time.AfterFunc(s.cfg.Window, s.tick)
+	}
+	d := s.pending
+	d.Total++
+	if r.Time.After(d.End) {
+		d.End = r.Time
+	}
+	if i, ok := s.index[key]; ok {
+		d.Alerts[i].Count++
+		d.Alerts[i].Last = r.Time
+		return nil
+	}
+	if len(d.Alerts) >= s.cfg.MaxAlerts {
+		d.Omitted++
+		return nil
+	}
+	s.index[key] = len(d.Alerts)
+	d.Alerts = append(d.Alerts, WebhookAlert{
+		First:   r.Time,
+		Last:    r.Time,
+		Fields:  copyFields(r.Fields),
+		Prefix:  r.Prefix,
+		Message: r.Message,
+		Level:   r.Level,
+		Count:   1,
+	})
+	return nil
+}
+
+// tick отправляет сообщение по истечении окна группировки
+func (s *WebhookSink) tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.cut(false)
+	}
+}
+
+// cut передает накопленное сообщение в очередь отправки. Вызывается под блокировкой;
+// при wait ожидает места в очереди, иначе отбрасывает сообщение при переполнении
+func (s *WebhookSink) cut(wait bool) {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if s.pending == nil {
+		return
+	}
+	digest := *s.pending
+	digest.Suppressed = s.suppressed
+	s.pending = nil
+	s.suppressed = 0
+
+	now := time.Now()
+	for key := range s.index {
+		s.lastSent[key] = now
+	}
+	s.index = nil
+	// Забываем оповещения, период подавления которых истек
+	for key, last := range s.lastSent {
+		if now.Sub(last) >= s.cfg.Throttle {
+			delete(s.lastSent, key)
+		}
+	}
+
+	if wait {
+		s.queue <- digest
+		return
+	}
+	select {
+	case s.queue <- digest:
+	default:
+		atomic.AddUint64(&s.stats.FailedMessages, 1)
+		reportSinkError("webhook", fmt.Errorf("очередь сообщений переполнена"))
+	}
+}
+
+// send формирует сообщение по шаблону и отправляет его
+func (s *WebhookSink) send(digest WebhookDigest) {
+	var body bytes.Buffer
+	err := s.tmpl.Execute(&body, digest)
+	var build func() (*http.Request, error)
+	if err == nil {
+		build, err = newBodyRequest(http.MethodPost, s.cfg.URL, "application/json", s.cfg.Headers, body.Bytes(), false)
+	}
+	if err == nil {
+		_, err = s.poster.do(build)
+	}
+	if err != nil {
+		atomic.AddUint64(&s.stats.FailedMessages, 1)
+		reportSinkError("webhook", err)
+		return
+	}
+	atomic.AddUint64(&s.stats.SentMessages, 1)
+}
+
+// Stats возвращает счетчики назначения
+func (s *WebhookSink) Stats() WebhookStats {
+	return WebhookStats{
+		SentMessages:    atomic.LoadUint64(&s.stats.SentMessages),
+		FailedMessages:  atomic.LoadUint64(&s.stats.FailedMessages),
+		Alerts:          atomic.LoadUint64(&s.stats.Alerts),
+		ThrottledAlerts: atomic.LoadUint64(&s.stats.ThrottledAlerts),
+	}
+}
+
+// Close реализует интерфейс Sink. Отправляет накопленное сообщение, не дожидаясь
+// окончания окна, и ждет завершения отправки
+func (s *WebhookSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		s.cut(true)
+		close(s.queue)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
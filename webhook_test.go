@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSinkSlackDigest(t *testing.T) {
+	server := newCollectingServer(t)
+
+	sink, err := NewWebhookSink(WebhookConfig{URL: server.URL, Format: WebhookSlack, Title: "billing alerts"})
+	require.NoError(t, err)
+
+	l := New().WithSink(sink)
+	l.WithPrefix("DB").Error("connection lost")
+	l.WithPrefix("DB").Error("connection lost")
+	l.WithPrefix("API").Error("timeout")
+	l.Info("not an alert")
+	l.Warning("not an alert either")
+	require.NoError(t, l.Close())
+
+	// Все записи окна отправляются одним сообщением при закрытии
+	bodies := server.Bodies()
+	require.Len(t, bodies, 1)
+	var payload map[string]string
+	require.NoError(t, json.Unmarshal([]byte(bodies[0]), &payload))
+	lines := strings.Split(payload["text"], "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "*billing alerts*", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "3 records on "))
+	assert.Equal(t, "[ERROR] [DB] connection lost (x2)", lines[2])
+	assert.Equal(t, "[ERROR] [API] timeout", lines[3])
+
+	stats := sink.Stats()
+	assert.Equal(t, uint64(1), stats.SentMessages)
+	assert.Equal(t, uint64(3), stats.Alerts)
+}
+
+func TestWebhookSinkWindowAndThrottle(t *testing.T) {
+	server := newCollectingServer(t)
+
+	sink, err := NewWebhookSink(WebhookConfig{URL: server.URL, Window: 20 * time.Millisecond, Throttle: time.Minute})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: ErrorLevel, Message: "disk full"}))
+	require.Eventually(t, func() bool { return len(server.Bodies()) == 1 }, 5*time.Second, 5*time.Millisecond)
+
+	// Повтор уже отправленного оповещения подавляется и учитывается в следующем сообщении
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: ErrorLevel, Message: "disk full"}))
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: FatalLevel, Message: "out of memory"}))
+	require.Eventually(t, func() bool { return len(server.Bodies()) == 2 }, 5*time.Second, 5*time.Millisecond)
+
+	var digest struct {
+		Alerts []struct {
+			Level   string `json:"level"`
+			Message string `json:"message"`
+			Count   int    `json:"count"`
+		} `json:"alerts"`
+		Total      int `json:"total"`
+		Suppressed int `json:"suppressed"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(server.Bodies()[1]), &digest))
+	assert.Equal(t, 1, digest.Total)
+	assert.Equal(t, 1, digest.Suppressed)
+	require.Len(t, digest.Alerts, 1)
+	assert.Equal(t, "FATAL", digest.Alerts[0].Level)
+	assert.Equal(t, "out of memory", digest.Alerts[0].Message)
+
+	stats := sink.Stats()
+	assert.Equal(t, uint64(2), stats.Alerts)
+	assert.Equal(t, uint64(1), stats.ThrottledAlerts)
+}
+
+func TestWebhookSinkGenericAndTeams(t *testing.T) {
+	server := newCollectingServer(t)
+
+	sink, err := NewWebhookSink(WebhookConfig{URL: server.URL, MaxAlerts: 1, Levels: []Level{WarningLevel, FatalLevel}})
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: WarningLevel, Prefix: "API", Message: "slow", Fields: map[string]interface{}{"ms": 900}}))
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: FatalLevel, Message: "crash"}))
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: ErrorLevel, Message: "ignored"}))
+	require.NoError(t, sink.Close())
+
+	var generic map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(server.Bodies()[0]), &generic))
+	assert.Equal(t, DefaultWebhookTitle, generic["title"])
+	assert.Equal(t, float64(2), generic["total"])
+	assert.Equal(t, float64(1), generic["omitted"])
+	alert := generic["alerts"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "WARNING", alert["level"])
+	assert.Equal(t, "API", alert["prefix"])
+	assert.Equal(t, map[string]interface{}{"ms": float64(900)}, alert["fields"])
+
+	sink, err = NewWebhookSink(WebhookConfig{URL: server.URL, Format: WebhookTeams})
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: FatalLevel, Message: "crash"}))
+	require.NoError(t, sink.Close())
+
+	var card map[string]string
+	require.NoError(t, json.Unmarshal([]byte(server.Bodies()[1]), &card))
+	assert.Equal(t, "MessageCard", card["@type"])
+	assert.Equal(t, "8B0000", card["themeColor"])
+	assert.Contains(t, card["text"], "\n\n[FATAL] crash")
+}
+
+func TestWebhookSinkCustomTemplate(t *testing.T) {
+	server := newCollectingServer(t, http.StatusInternalServerError)
+
+	sink, err := NewWebhookSink(WebhookConfig{
+		URL:         server.URL,
+		Template:    `{"summary":{{json (index .Lines 1)}}}`,
+		RetryConfig: RetryConfig{MinBackoff: time.Millisecond},
+	})
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(&Record{Time: time.Now(), Level: ErrorLevel, Message: "boom"}))
+	require.NoError(t, sink.Close())
+
+	// Первая попытка получила 500, сообщение отправлено повторно
+	require.Len(t, server.Bodies(), 1)
+	assert.JSONEq(t, `{"summary":"[ERROR] boom"}`, server.Bodies()[0])
+	assert.Equal(t, uint64(1), sink.Stats().SentMessages)
+	assert.Error(t, sink.Write(&Record{Level: ErrorLevel, Message: "late"}))
+
+	var cfgErr *ConfigError
+	_, err = NewWebhookSink(WebhookConfig{URL: server.URL, Template: "{{.Missing"})
+	assert.ErrorAs(t, err, &cfgErr)
+	_, err = NewWebhookSink(WebhookConfig{URL: server.URL, Format: "discord"})
+	assert.ErrorAs(t, err, &cfgErr)
+}